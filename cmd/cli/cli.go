@@ -1,24 +1,105 @@
 package cli
 
 import (
+	"bytes"
+	"flag"
+	"io"
 	"log"
 	"os"
 
 	"bitbucket.org/yujiorama/jira-timespent-report/jira"
 )
 
-func Do() {
+const (
+	exitLateChange   = 1
+	exitMailError    = 2
+	exitNotifyError  = 3
+	exitCommandError = 4
+)
+
+var progressEnable bool
+
+func init() {
+	flag.BoolVar(&progressEnable, "progress", false, "print progress bar to stderr")
+}
+
+var commands = map[string]func(w io.Writer) []error{
+	"sync":      jira.Sync,
+	"snapshots": jira.Snapshots,
+	"diff":      jira.Diff,
+	"lock":      jira.Lock,
+	"unlock":    jira.Unlock,
+	"check":     jira.Check,
+}
+
+func Do() int {
 	log.Println("start")
 
+	if progressEnable {
+		jira.SetProgress((&progressBar{w: os.Stderr}).update)
+	}
+
+	if command, ok := commands[jira.Command()]; ok {
+		status := 0
+		for _, err := range command(os.Stdout) {
+			log.Printf("%v\n", err)
+			if !jira.IsLateChange(err) {
+				status = exitCommandError
+			} else if status == 0 {
+				status = exitLateChange
+			}
+		}
+
+		log.Println("end")
+		return status
+	}
+
+	if jira.IsDryRun() {
+		dryRunErrors := jira.DryRun(os.Stdout)
+		for _, err := range dryRunErrors {
+			log.Printf("%v\n", err)
+		}
+
+		log.Println("end")
+		if len(dryRunErrors) > 0 {
+			return exitCommandError
+		}
+		return 0
+	}
+
 	issues, worklogs, searchErrors := jira.Search()
 	for _, err := range searchErrors {
 		log.Printf("%v\n", err)
 	}
 
-	reportErrors := jira.Report(os.Stdout, issues, worklogs)
+	var buf bytes.Buffer
+	reportErrors := jira.Report(io.MultiWriter(os.Stdout, &buf), issues, worklogs)
 	for _, err := range reportErrors {
 		log.Printf("%v\n", err)
 	}
 
+	if jira.IsMail() && len(searchErrors) == 0 && len(reportErrors) == 0 {
+		if err := jira.MailReport(buf.Bytes(), issues, worklogs); err != nil {
+			log.Printf("%v\n", err)
+			log.Println("end")
+			return exitMailError
+		}
+	}
+
+	if jira.IsNotify() && len(searchErrors) == 0 && len(reportErrors) == 0 {
+		notifyErrors := jira.NotifyReport(issues, worklogs)
+		for _, err := range notifyErrors {
+			log.Printf("%v\n", err)
+		}
+		if len(notifyErrors) > 0 {
+			log.Println("end")
+			return exitNotifyError
+		}
+	}
+
 	log.Println("end")
+	if len(searchErrors) > 0 || len(reportErrors) > 0 {
+		return exitCommandError
+	}
+	return 0
 }
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"bitbucket.org/yujiorama/jira-timespent-report/jira"
+)
+
+const progressBarWidth = 30
+
+type progressBar struct {
+	mutex sync.Mutex
+	w     io.Writer
+}
+
+func (b *progressBar) update(event jira.ProgressEvent) {
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if event.Kind == jira.ProgressDone {
+		fmt.Fprintf(b.w, "\r%-80s\n", b.line(event))
+		return
+	}
+	fmt.Fprintf(b.w, "\r%-80s", b.line(event))
+}
+
+func (b *progressBar) line(event jira.ProgressEvent) string {
+
+	if event.Issues == 0 {
+		return fmt.Sprintf("searching: %d pages, %d issues, %d errors, %d retries",
+			event.Pages, event.IssuesFound, event.Errors, event.Retries)
+	}
+
+	filled := progressBarWidth * event.IssuesProcessed / event.Issues
+	if filled > progressBarWidth {
+		filled = progressBarWidth
+	}
+
+	return fmt.Sprintf("[%s%s] %d/%d issues, %d worklogs, %d errors, %d retries",
+		strings.Repeat("#", filled), strings.Repeat(".", progressBarWidth-filled),
+		event.IssuesProcessed, event.Issues, event.Worklogs, event.Errors, event.Retries)
+}
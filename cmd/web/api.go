@@ -0,0 +1,108 @@
+package web
+
+import (
+	_ "embed"
+	"log"
+	"mime"
+	"net/http"
+
+	"bitbucket.org/yujiorama/jira-timespent-report/jira"
+)
+
+const maxApiRequestSize = 1 << 20
+
+//go:embed openapi.json
+var openapi []byte
+
+func apiRequest(w http.ResponseWriter, r *http.Request) bool {
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJson(w, http.StatusMethodNotAllowed, &jira.ApiErrorResponse{Errors: jira.ApiErrors{{Message: "method must be POST"}}})
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		writeJson(w, http.StatusUnsupportedMediaType, &jira.ApiErrorResponse{Errors: jira.ApiErrors{{Message: "content type must be application/json"}}})
+		return false
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxApiRequestSize)
+	return true
+}
+
+func apiResponse(w http.ResponseWriter, response interface{}, searchErrors []error) {
+
+	if len(searchErrors) > 0 {
+		for _, err := range searchErrors {
+			log.Printf("%v\n", err)
+		}
+		writeJson(w, http.StatusInternalServerError, jira.NewApiErrorResponse(searchErrors))
+		return
+	}
+
+	writeJson(w, http.StatusOK, response)
+}
+
+func apiIssuesHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !apiRequest(w, r) {
+		return
+	}
+
+	req, err := jira.DecodeApiSearchRequest(r.Body)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, jira.NewApiErrorResponse([]error{err}))
+		return
+	}
+
+	response, searchErrors := jira.SearchIssues(req)
+	apiResponse(w, response, searchErrors)
+}
+
+func apiWorklogsHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !apiRequest(w, r) {
+		return
+	}
+
+	req, err := jira.DecodeApiSearchRequest(r.Body)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, jira.NewApiErrorResponse([]error{err}))
+		return
+	}
+
+	response, searchErrors := jira.SearchWorklogs(req)
+	apiResponse(w, response, searchErrors)
+}
+
+func apiReportHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !apiRequest(w, r) {
+		return
+	}
+
+	req, err := jira.DecodeApiReportRequest(r.Body)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, jira.NewApiErrorResponse([]error{err}))
+		return
+	}
+
+	response, reportErrors := jira.SearchReport(req)
+	apiResponse(w, response, reportErrors)
+}
+
+func openapiHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "application/json")
+	if _, err := w.Write(openapi); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,354 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"bitbucket.org/yujiorama/jira-timespent-report/jira"
+)
+
+const (
+	jobQueued    = "queued"
+	jobRunning   = "running"
+	jobSucceeded = "succeeded"
+	jobFailed    = "failed"
+
+	progressWaiting   = "waiting"
+	progressSearching = "searching"
+	progressRendering = "rendering"
+	progressDone      = "done"
+
+	DefaultJobQueueSize = 10
+	DefaultJobRetention = time.Hour
+)
+
+var (
+	jobQueueSize int
+	jobRetention time.Duration
+	jobs         *jobQueue
+)
+
+func init() {
+	flag.IntVar(&jobQueueSize, "job-queue", DefaultJobQueueSize, "number of report jobs waiting to run")
+	flag.DurationVar(&jobRetention, "job-retention", DefaultJobRetention, "how long finished report jobs are kept")
+}
+
+type job struct {
+	Id         string              `json:"id"`
+	Status     string              `json:"status"`
+	Progress   string              `json:"progress"`
+	Counts     *jira.ProgressEvent `json:"counts,omitempty"`
+	CreatedAt  time.Time           `json:"createdAt"`
+	StartedAt  *time.Time          `json:"startedAt,omitempty"`
+	FinishedAt *time.Time          `json:"finishedAt,omitempty"`
+	Errors     []string            `json:"errors,omitempty"`
+	Result     string              `json:"result,omitempty"`
+
+	params      url.Values
+	contentType string
+	body        []byte
+	changed     chan struct{}
+}
+
+type jobQueue struct {
+	mutex sync.Mutex
+	jobs  map[string]*job
+	queue chan *job
+}
+
+func newJobId() (string, error) {
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("rand.Read error: %v", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func startJobs(ctx context.Context) {
+
+	jobs = &jobQueue{jobs: map[string]*job{}, queue: make(chan *job, jobQueueSize)}
+	go jobs.work(ctx)
+	go jobs.expire(ctx)
+}
+
+func (q *jobQueue) submit(params url.Values) (job, error) {
+
+	id, err := newJobId()
+	if err != nil {
+		return job{}, err
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	j := &job{Id: id, Status: jobQueued, Progress: progressWaiting, CreatedAt: time.Now(), params: params, changed: make(chan struct{})}
+	select {
+	case q.queue <- j:
+	default:
+		return job{}, fmt.Errorf("job queue is full: %v", jobQueueSize)
+	}
+	q.jobs[id] = j
+
+	return *j, nil
+}
+
+func (q *jobQueue) get(id string) (job, bool) {
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	j, ok := q.jobs[id]
+	if !ok {
+		return job{}, false
+	}
+
+	return *j, true
+}
+
+func (q *jobQueue) update(j *job, f func(j *job)) {
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	f(j)
+
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+func (q *jobQueue) work(ctx context.Context) {
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-q.queue:
+			q.run(j)
+		}
+	}
+}
+
+func (q *jobQueue) run(j *job) {
+
+	q.update(j, func(j *job) {
+		now := time.Now()
+		j.Status = jobRunning
+		j.Progress = progressSearching
+		j.StartedAt = &now
+	})
+
+	var buf bytes.Buffer
+	contentType := "text/csv"
+	jobErrors := make([]error, 0, 10)
+	err := jira.WithQueryParams(j.params, func() {
+		jira.ClearCache()
+		jira.SetProgress(func(event jira.ProgressEvent) {
+			q.update(j, func(j *job) {
+				j.Counts = &event
+			})
+		})
+
+		if jira.IsDryRun() {
+			jobErrors = append(jobErrors, jira.DryRun(&buf)...)
+			return
+		}
+
+		issues, worklogs, searchErrors := jira.Search()
+		if len(searchErrors) > 0 {
+			jobErrors = append(jobErrors, searchErrors...)
+			return
+		}
+
+		q.update(j, func(j *job) {
+			j.Progress = progressRendering
+		})
+		if jira.IsJsonReport() {
+			contentType = "application/json"
+		}
+		jobErrors = append(jobErrors, jira.Report(&buf, issues, worklogs)...)
+	})
+	if err != nil {
+		jobErrors = append(jobErrors, err)
+	}
+
+	q.update(j, func(j *job) {
+		now := time.Now()
+		j.FinishedAt = &now
+		j.Progress = progressDone
+		if len(jobErrors) > 0 {
+			j.Status = jobFailed
+			for _, err := range jobErrors {
+				log.Printf("%v\n", err)
+				j.Errors = append(j.Errors, fmt.Sprintf("%v", err))
+			}
+			return
+		}
+		j.Status = jobSucceeded
+		j.Result = fmt.Sprintf("/jobs/%s/result", j.Id)
+		j.contentType = contentType
+		j.body = buf.Bytes()
+	})
+}
+
+func (q *jobQueue) expire(ctx context.Context) {
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			q.expireJobs(now)
+		}
+	}
+}
+
+func (q *jobQueue) expireJobs(now time.Time) {
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for id, j := range q.jobs {
+		if j.FinishedAt != nil && now.Sub(*j.FinishedAt) > jobRetention {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		log.Println(err)
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(body); err != nil {
+		log.Println(err)
+	}
+}
+
+func jobsHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeJson(w, http.StatusBadRequest, &errorResponse{Message: []string{err.Error()}})
+		return
+	}
+	if err := jira.ValidateQueryParams(r.Form); err != nil {
+		writeJson(w, http.StatusBadRequest, &errorResponse{Message: []string{err.Error()}})
+		return
+	}
+
+	j, err := jobs.submit(r.Form)
+	if err != nil {
+		writeJson(w, http.StatusServiceUnavailable, &errorResponse{Message: []string{err.Error()}})
+		return
+	}
+
+	w.Header().Set("Location", "/jobs/"+j.Id)
+	writeJson(w, http.StatusAccepted, &j)
+}
+
+func jobHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	paths := strings.Split(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/")
+	if len(paths) > 2 || (len(paths) == 2 && paths[1] != "result" && paths[1] != "events") {
+		http.NotFound(w, r)
+		return
+	}
+
+	j, ok := jobs.get(paths[0])
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if len(paths) == 1 {
+		writeJson(w, http.StatusOK, &j)
+		return
+	}
+
+	if paths[1] == "events" {
+		jobEvents(w, r, j)
+		return
+	}
+
+	switch j.Status {
+	case jobSucceeded:
+		h := w.Header()
+		h.Set("Content-Type", j.contentType)
+		if _, err := w.Write(j.body); err != nil {
+			log.Println(err)
+		}
+	case jobFailed:
+		handleError(&errorResponse{Message: j.Errors}, w)
+	default:
+		writeJson(w, http.StatusConflict, &errorResponse{Message: []string{fmt.Sprintf("job is %s", j.Status)}})
+	}
+}
+
+func jobEvents(w http.ResponseWriter, r *http.Request, j job) {
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		handleError(&errorResponse{Message: []string{"streaming is not supported"}}, w)
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+
+	for {
+		body, err := json.Marshal(&j)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", j.Status, body); err != nil {
+			log.Println(err)
+			return
+		}
+		flusher.Flush()
+
+		if j.FinishedAt != nil {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-j.changed:
+		}
+
+		if j, ok = jobs.get(j.Id); !ok {
+			return
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"bitbucket.org/yujiorama/jira-timespent-report/jira"
+)
+
+func submitJob(t *testing.T, ts *httptest.Server, params url.Values) (*http.Response, job) {
+	resp, err := http.PostForm(ts.URL+"/jobs", params)
+	if err != nil {
+		t.Fatalf("http.PostForm() error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	var j job
+	if resp.StatusCode == http.StatusAccepted {
+		if err := json.NewDecoder(resp.Body).Decode(&j); err != nil {
+			t.Fatalf("json.Decode() error = %v", err)
+		}
+	}
+
+	return resp, j
+}
+
+func waitJob(t *testing.T, ts *httptest.Server, id string) job {
+	for i := 0; i < 100; i++ {
+		var j job
+		if status := getJson(t, ts.URL+"/jobs/"+id, &j); status != http.StatusOK {
+			t.Fatalf("expected=[%v] <> actual[%v]\n", http.StatusOK, status)
+		}
+		if j.FinishedAt != nil {
+			return j
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	t.Fatalf("job is not finished: %v", id)
+	return job{}
+}
+
+func TestJobs(t *testing.T) {
+	server, ts := setupFakeJira(t, nil)
+
+	resp, submitted := submitJob(t, ts, url.Values{"report": []string{"timesheet"}})
+	if resp.StatusCode != http.StatusAccepted || resp.Header.Get("Location") != "/jobs/"+submitted.Id {
+		t.Fatalf("expected=[%v /jobs/%v] <> actual[%v %v]\n", http.StatusAccepted, submitted.Id, resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	j := waitJob(t, ts, submitted.Id)
+	if j.Status != jobSucceeded || j.Progress != progressDone || j.Result != "/jobs/"+j.Id+"/result" {
+		t.Errorf("expected=[%v %v] <> actual[%v %v %v]\n", jobSucceeded, progressDone, j.Status, j.Progress, j.Errors)
+	}
+
+	resp, body := get(t, ts.URL+j.Result)
+	expected := "表示名,メールアドレス,アカウントID,消費時間,所定時間,差分\n" +
+		"Alice,alice@example.com,5b10a2844c20165700ede21g,11.00,160.00,-149.00\n" +
+		"Bob,bob@example.com,5b10ac8d82e05b22cc7d4ef5,8.00,160.00,-152.00\n"
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/csv" || body != expected {
+		t.Errorf("expected=[%v] <> actual[%v %v]\n", expected, resp.StatusCode, body)
+	}
+
+	requests := server.Requests("search/jql")
+	_, submitted = submitJob(t, ts, url.Values{"report": []string{"timesheet"}})
+	if j := waitJob(t, ts, submitted.Id); j.Status != jobSucceeded {
+		t.Errorf("expected=[%v] <> actual[%v %v]\n", jobSucceeded, j.Status, j.Errors)
+	}
+	if actual := server.Requests("search/jql"); actual != 2*requests {
+		t.Errorf("expected=[%v] <> actual[%v]\n", 2*requests, actual)
+	}
+
+	if resp, _ := submitJob(t, ts, url.Values{"namedquery": []string{"project = DEMO"}}); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected=[%v] <> actual[%v]\n", http.StatusBadRequest, resp.StatusCode)
+	}
+	if status := getJson(t, ts.URL+"/jobs/unknown", &j); status != http.StatusNotFound {
+		t.Errorf("expected=[%v] <> actual[%v]\n", http.StatusNotFound, status)
+	}
+}
+
+func TestJobs_Failed(t *testing.T) {
+	server, ts := setupFakeJira(t, nil)
+	server.FailNext(100)
+
+	_, submitted := submitJob(t, ts, url.Values{})
+	j := waitJob(t, ts, submitted.Id)
+	if j.Status != jobFailed || len(j.Errors) == 0 || len(j.Result) > 0 {
+		t.Errorf("expected=[%v] <> actual[%v %v]\n", jobFailed, j.Status, j.Errors)
+	}
+
+	if resp, body := get(t, ts.URL+"/jobs/"+j.Id+"/result"); resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected=[%v] <> actual[%v %v]\n", http.StatusInternalServerError, resp.StatusCode, body)
+	}
+}
+
+func TestJobs_QueueFull(t *testing.T) {
+	_, ts := setupFakeJira(t, nil)
+	saved := jobQueueSize
+	defer func() { jobQueueSize = saved }()
+	jobQueueSize = 1
+	jobs = &jobQueue{jobs: map[string]*job{}, queue: make(chan *job, jobQueueSize)}
+
+	resp, submitted := submitJob(t, ts, url.Values{})
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected=[%v] <> actual[%v]\n", http.StatusAccepted, resp.StatusCode)
+	}
+	if resp, _ := submitJob(t, ts, url.Values{}); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected=[%v] <> actual[%v]\n", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	var j job
+	if status := getJson(t, ts.URL+"/jobs/"+submitted.Id, &j); status != http.StatusOK || j.Status != jobQueued || j.Progress != progressWaiting {
+		t.Errorf("expected=[%v %v] <> actual[%v %v]\n", http.StatusOK, jobQueued, status, j.Status)
+	}
+	if resp, _ := get(t, ts.URL+"/jobs/"+submitted.Id+"/result"); resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected=[%v] <> actual[%v]\n", http.StatusConflict, resp.StatusCode)
+	}
+}
+
+func TestJobs_Retention(t *testing.T) {
+	_, ts := setupFakeJira(t, nil)
+
+	_, submitted := submitJob(t, ts, url.Values{})
+	j := waitJob(t, ts, submitted.Id)
+
+	jobs.expireJobs(j.FinishedAt.Add(jobRetention))
+	if status := getJson(t, ts.URL+"/jobs/"+j.Id, &j); status != http.StatusOK {
+		t.Errorf("expected=[%v] <> actual[%v]\n", http.StatusOK, status)
+	}
+
+	jobs.expireJobs(j.FinishedAt.Add(jobRetention + time.Second))
+	if status := getJson(t, ts.URL+"/jobs/"+j.Id, &j); status != http.StatusNotFound {
+		t.Errorf("expected=[%v] <> actual[%v]\n", http.StatusNotFound, status)
+	}
+	if resp, _ := get(t, ts.URL+"/jobs/"+j.Id+"/result"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected=[%v] <> actual[%v]\n", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+type jobEvent struct {
+	name string
+	job  job
+}
+
+func TestJobs_Events(t *testing.T) {
+	server, ts := setupFakeJira(t, map[string]string{"max-retry": "1"})
+	server.FailNext(1)
+	jobs = &jobQueue{jobs: map[string]*job{}, queue: make(chan *job, jobQueueSize)}
+
+	_, submitted := submitJob(t, ts, url.Values{"worklog": []string{"true"}})
+	resp, err := http.Get(ts.URL + "/jobs/" + submitted.Id + "/events")
+	if err != nil {
+		t.Fatalf("http.Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected=[text/event-stream] <> actual[%v]\n", resp.Header.Get("Content-Type"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make([]jobEvent, 0, 10)
+	event := jobEvent{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.job); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v\nline=[%v]", err, line)
+			}
+		case len(line) == 0:
+			events = append(events, event)
+			event = jobEvent{}
+			if len(events) == 1 {
+				go jobs.work(ctx)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scanner.Err() error = %v", err)
+	}
+
+	if len(events) < 3 || events[0].name != jobQueued || events[len(events)-1].name != jobSucceeded {
+		t.Fatalf("expected=[%v ... %v] <> actual[%v]\n", jobQueued, jobSucceeded, events)
+	}
+	retried := false
+	previous := jira.ProgressEvent{}
+	for i, event := range events[1 : len(events)-1] {
+		if event.name != jobRunning || event.name != event.job.Status {
+			t.Errorf("%v: expected=[%v] <> actual[%v]\n", i+1, jobRunning, event.name)
+		}
+		if event.job.Counts == nil {
+			continue
+		}
+		counts := *event.job.Counts
+		if counts.Pages < previous.Pages || counts.Issues < previous.Issues || counts.Worklogs < previous.Worklogs || counts.Retries < previous.Retries {
+			t.Errorf("%v: expected=[>= %v] <> actual[%v]\n", i+1, previous, counts)
+		}
+		if counts.Kind == "retry" {
+			retried = true
+		}
+		previous = counts
+	}
+	if !retried {
+		t.Errorf("expected=[retry event] <> actual[%v]\n", events)
+	}
+
+	last := events[len(events)-1].job
+	if last.Progress != progressDone || last.Counts == nil {
+		t.Fatalf("expected=[%v] <> actual[%v]\n", progressDone, last)
+	}
+	expected := jira.ProgressEvent{Kind: last.Counts.Kind, Pages: 2, IssuesFound: 3, Issues: 3, IssuesProcessed: 3, Worklogs: 4, Retries: 1}
+	if *last.Counts != expected {
+		t.Errorf("expected=[%v] <> actual[%v]\n", expected, *last.Counts)
+	}
+}
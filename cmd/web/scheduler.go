@@ -0,0 +1,125 @@
+package web
+
+import (
+	"context"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"bitbucket.org/yujiorama/jira-timespent-report/jira"
+)
+
+const maxRuns = 100
+
+var runsTemplate = template.Must(template.New("runs").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<title>jira-timespent-report runs</title>
+</head>
+<body>
+<h1>スケジュール</h1>
+<table border="1">
+<tr><th>名前</th><th>cron</th><th>形式</th><th>送信先</th><th>次回実行日時</th></tr>
+{{range .Schedules}}<tr><td>{{.Name}}</td><td>{{.Cron}}</td><td>{{.Format}}</td><td>{{.Destination}}</td><td>{{.Next}}</td></tr>
+{{end}}</table>
+<h1>実行履歴</h1>
+<table border="1">
+<tr><th>ID</th><th>名前</th><th>開始日時</th><th>終了日時</th><th>状態</th><th>送信先</th><th>メッセージ</th></tr>
+{{range .Runs}}<tr><td>{{.Id}}</td><td>{{.Schedule}}</td><td>{{.StartedAt.Format "2006-01-02 15:04:05"}}</td><td>{{.FinishedAt.Format "2006-01-02 15:04:05"}}</td><td>{{.Status}}</td><td>{{.Destination}}</td><td><pre>{{.Message}}</pre></td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+type scheduleView struct {
+	Name        string
+	Cron        string
+	Format      string
+	Destination string
+	Next        string
+}
+
+type runsView struct {
+	Schedules []scheduleView
+	Runs      []jira.Run
+}
+
+func startScheduler(ctx context.Context) error {
+
+	schedules, err := jira.Schedules()
+	if err != nil {
+		return err
+	}
+
+	for _, schedule := range schedules {
+		go runScheduler(ctx, schedule)
+	}
+
+	return nil
+}
+
+func runScheduler(ctx context.Context, schedule jira.Schedule) {
+
+	for {
+		next, ok := schedule.Next(time.Now())
+		if !ok {
+			log.Printf("schedule never runs: name=[%v]\n", schedule.Name)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		run, err := jira.RunSchedule(schedule)
+		if err != nil {
+			log.Printf("RunSchedule error: %v\n", err)
+		}
+		log.Printf("run: name=[%v],status=[%v],destination=[%v]\n", run.Schedule, run.Status, run.Destination)
+	}
+}
+
+func runsHandler(w http.ResponseWriter, r *http.Request) {
+
+	schedules, err := jira.Schedules()
+	if err != nil {
+		log.Printf("%v\n", err)
+		handleError(&errorResponse{Message: []string{err.Error()}}, w)
+		return
+	}
+
+	runs, err := jira.Runs(maxRuns)
+	if err != nil {
+		log.Printf("%v\n", err)
+		handleError(&errorResponse{Message: []string{err.Error()}}, w)
+		return
+	}
+
+	view := runsView{Schedules: make([]scheduleView, 0, len(schedules)), Runs: runs}
+	now := time.Now()
+	for _, schedule := range schedules {
+		next := ""
+		if t, ok := schedule.Next(now); ok {
+			next = t.Format("2006-01-02 15:04")
+		}
+		view.Schedules = append(view.Schedules, scheduleView{
+			Name:        schedule.Name,
+			Cron:        schedule.Cron,
+			Format:      schedule.Format,
+			Destination: schedule.Destination.String(),
+			Next:        next,
+		})
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	if err := runsTemplate.Execute(w, view); err != nil {
+		log.Println(err)
+	}
+}
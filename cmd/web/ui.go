@@ -0,0 +1,46 @@
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"log"
+	"net/http"
+)
+
+//go:embed ui
+var ui embed.FS
+
+func uiHandler() http.Handler {
+
+	assets, err := fs.Sub(ui, "ui")
+	if err != nil {
+		log.Fatalf("fs.Sub: %v", err)
+	}
+
+	return http.StripPrefix("/ui/", http.FileServer(http.FS(assets)))
+}
+
+func indexHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if len(r.URL.RawQuery) > 0 {
+		http.Redirect(w, r, "/report?"+r.URL.RawQuery, http.StatusPermanentRedirect)
+		return
+	}
+
+	body, err := ui.ReadFile("ui/index.html")
+	if err != nil {
+		handleError(&errorResponse{Message: []string{err.Error()}}, w)
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write(body); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,166 @@
+(function () {
+  'use strict';
+
+  var form = document.getElementById('report-form');
+  var status = document.getElementById('status');
+  var download = document.getElementById('download');
+  var preview = document.getElementById('preview');
+
+  function parseCsv(text) {
+    var rows = [];
+    var row = [];
+    var field = '';
+    var quoted = false;
+    for (var i = 0; i < text.length; i++) {
+      var c = text[i];
+      if (quoted) {
+        if (c === '"' && text[i + 1] === '"') {
+          field += '"';
+          i++;
+        } else if (c === '"') {
+          quoted = false;
+        } else {
+          field += c;
+        }
+      } else if (c === '"') {
+        quoted = true;
+      } else if (c === ',') {
+        row.push(field);
+        field = '';
+      } else if (c === '\n') {
+        row.push(field);
+        rows.push(row);
+        row = [];
+        field = '';
+      } else if (c !== '\r') {
+        field += c;
+      }
+    }
+    if (field.length > 0 || row.length > 0) {
+      row.push(field);
+      rows.push(row);
+    }
+    return rows;
+  }
+
+  function renderTables(rows) {
+    preview.textContent = '';
+    var table = null;
+    var columns = 0;
+    rows.forEach(function (row) {
+      if (table === null || row.length !== columns) {
+        table = document.createElement('table');
+        preview.appendChild(table);
+        columns = row.length;
+        var header = table.insertRow();
+        row.forEach(function (value) {
+          var th = document.createElement('th');
+          th.textContent = value;
+          header.appendChild(th);
+        });
+        return;
+      }
+      var tr = table.insertRow();
+      row.forEach(function (value) {
+        var td = tr.insertCell();
+        td.textContent = value;
+        if (/^-?\d+(\.\d+)?$/.test(value)) {
+          td.className = 'number';
+        }
+      });
+    });
+  }
+
+  function renderJson(text) {
+    preview.textContent = '';
+    var pre = document.createElement('pre');
+    pre.textContent = JSON.stringify(JSON.parse(text), null, 2);
+    preview.appendChild(pre);
+  }
+
+  function showProgress(job) {
+    var counts = job.counts || {};
+    var text = job.status + ' (' + job.progress + ')';
+    if (counts.issues > 0) {
+      text += ' ' + counts.issuesProcessed + '/' + counts.issues + ' issues, ' + counts.worklogs + ' worklogs';
+    } else if (counts.pages > 0) {
+      text += ' ' + counts.pages + ' pages, ' + counts.issuesFound + ' issues';
+    }
+    if (counts.errors > 0) {
+      text += ', ' + counts.errors + ' errors';
+    }
+    status.className = '';
+    status.textContent = text;
+  }
+
+  function showResult(job, isJson) {
+    fetch(job.result).then(function (response) {
+      if (!response.ok) {
+        return response.json().then(function (body) {
+          throw new Error(body.message.join('\n'));
+        });
+      }
+      return response.text();
+    }).then(function (text) {
+      if (isJson) {
+        renderJson(text);
+      } else {
+        renderTables(parseCsv(text));
+      }
+      download.href = job.result;
+      download.download = 'report.' + (isJson ? 'json' : 'csv');
+      download.hidden = false;
+    }).catch(showError);
+  }
+
+  function showError(error) {
+    status.className = 'failed';
+    status.textContent = String(error.message || error);
+  }
+
+  form.addEventListener('submit', function (event) {
+    event.preventDefault();
+    download.hidden = true;
+    preview.textContent = '';
+
+    var params = new URLSearchParams();
+    new FormData(form).forEach(function (value, key) {
+      if (value !== '') {
+        params.append(key, value);
+      }
+    });
+    var isJson = params.get('report') === 'json';
+
+    fetch('/jobs', { method: 'POST', body: params }).then(function (response) {
+      return response.json().then(function (body) {
+        if (!response.ok) {
+          throw new Error(body.message.join('\n'));
+        }
+        return body;
+      });
+    }).then(function (job) {
+      showProgress(job);
+      var events = new EventSource('/jobs/' + job.id + '/events');
+      var finish = function (e) {
+        events.close();
+        var finished = JSON.parse(e.data);
+        if (finished.status === 'failed') {
+          showError(finished.errors.join('\n'));
+          return;
+        }
+        showProgress(finished);
+        showResult(finished, isJson);
+      };
+      ['queued', 'running'].forEach(function (name) {
+        events.addEventListener(name, function (e) {
+          showProgress(JSON.parse(e.data));
+        });
+      });
+      events.addEventListener('succeeded', finish);
+      events.addEventListener('failed', finish);
+      events.onerror = function () {
+        events.close();
+      };
+    }).catch(showError);
+  });
+}());
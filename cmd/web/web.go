@@ -1,6 +1,7 @@
 package web
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"flag"
@@ -10,6 +11,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -44,8 +46,13 @@ func Do() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", reportHandler)
+	mux := newServeMux()
+
+	startJobs(ctx)
+
+	if err := startScheduler(ctx); err != nil {
+		log.Fatalf("startScheduler: %v", err)
+	}
 
 	server := &http.Server{
 		Addr:        fmt.Sprintf("%s:%d", host, port),
@@ -82,6 +89,24 @@ func Do() {
 	defer os.Exit(0)
 }
 
+func newServeMux() *http.ServeMux {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", indexHandler)
+	mux.Handle("/ui/", uiHandler())
+	mux.HandleFunc("/report", reportHandler)
+	mux.HandleFunc("/diff", diffHandler)
+	mux.HandleFunc("/runs", runsHandler)
+	mux.HandleFunc("/jobs", jobsHandler)
+	mux.HandleFunc("/jobs/", jobHandler)
+	mux.HandleFunc("/api/v1/report", apiReportHandler)
+	mux.HandleFunc("/api/v1/worklogs", apiWorklogsHandler)
+	mux.HandleFunc("/api/v1/issues", apiIssuesHandler)
+	mux.HandleFunc("/api/v1/openapi.json", openapiHandler)
+
+	return mux
+}
+
 type errorResponse struct {
 	Message []string `json:"message"`
 }
@@ -104,7 +129,25 @@ func handleError(responseBody *errorResponse, w http.ResponseWriter) {
 
 func reportHandler(w http.ResponseWriter, r *http.Request) {
 
-	jira.SetQueryParams(r.URL.Query())
+	err := jira.WithQueryParams(r.URL.Query(), func() {
+		report(w)
+	})
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, &errorResponse{Message: []string{err.Error()}})
+	}
+}
+
+func report(w http.ResponseWriter) {
+
+	if jira.IsDryRun() {
+		h := w.Header()
+		h.Set("Content-Type", "text/csv")
+		dryRunErrors := jira.DryRun(w)
+		for _, err := range dryRunErrors {
+			log.Printf("%v\n", err)
+		}
+		return
+	}
 
 	issues, worklogs, searchErrors := jira.Search()
 	if len(searchErrors) > 0 {
@@ -127,3 +170,47 @@ func reportHandler(w http.ResponseWriter, r *http.Request) {
 		log.Printf("%v\n", err)
 	}
 }
+
+func diffHandler(w http.ResponseWriter, r *http.Request) {
+
+	var buf bytes.Buffer
+	var diffErrors []error
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		err = jira.WithQueryParams(r.URL.Query(), func() {
+			diffErrors = jira.Diff(&buf)
+		})
+	case http.MethodPost:
+		isJson := strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+		err = jira.WithQueryParams(r.URL.Query(), func() {
+			diffErrors = jira.DiffExport(&buf, r.Body, isJson)
+		})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, &errorResponse{Message: []string{err.Error()}})
+		return
+	}
+
+	if len(diffErrors) > 0 {
+		message := make([]string, 0, 10)
+		for _, err := range diffErrors {
+			log.Printf("%v\n", err)
+			message = append(message, fmt.Sprintf("%v", err))
+		}
+
+		responseBody := &errorResponse{Message: message}
+
+		handleError(responseBody, w)
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/csv")
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,151 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"bitbucket.org/yujiorama/jira-timespent-report/jira/jiratest"
+)
+
+func setupFakeJira(t *testing.T, flags map[string]string) (*jiratest.Server, *httptest.Server) {
+	server, err := jiratest.NewServer()
+	if err != nil {
+		t.Fatalf("jiratest.NewServer() error = %v", err)
+	}
+
+	values := map[string]string{
+		"url":       server.URL,
+		"query":     "project = DEMO",
+		"fields":    "summary,timespent",
+		"maxresult": "2",
+		"max-retry": "0",
+		"unit":      "hh",
+		"targetym":  "2020-08",
+		"report":    "timespent",
+	}
+	for name, value := range flags {
+		values[name] = value
+	}
+
+	saved := map[string]string{}
+	for name, value := range values {
+		saved[name] = flag.Lookup(name).Value.String()
+		if err := flag.Set(name, value); err != nil {
+			t.Fatalf("flag.Set(%v) error = %v", name, err)
+		}
+	}
+	user, token := os.Getenv("AUTH_USER"), os.Getenv("AUTH_TOKEN")
+	os.Setenv("AUTH_USER", server.User)
+	os.Setenv("AUTH_TOKEN", server.Token)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	startJobs(ctx)
+	ts := httptest.NewServer(newServeMux())
+
+	t.Cleanup(func() {
+		ts.Close()
+		cancel()
+		server.Close()
+		for name, value := range saved {
+			flag.Set(name, value)
+		}
+		os.Setenv("AUTH_USER", user)
+		os.Setenv("AUTH_TOKEN", token)
+	})
+
+	return server, ts
+}
+
+func get(t *testing.T, url string) (*http.Response, string) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("http.Get(%v) error = %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll() error = %v", err)
+	}
+
+	return resp, string(body)
+}
+
+func getJson(t *testing.T, url string, v interface{}) int {
+	resp, body := get(t, url)
+	if resp.StatusCode == http.StatusOK {
+		if err := json.Unmarshal([]byte(body), v); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v\nbody=[%v]", err, body)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+func TestUiHandlers(t *testing.T) {
+	_, ts := setupFakeJira(t, nil)
+
+	tests := []struct {
+		path        string
+		asset       string
+		contentType string
+	}{
+		{"/", "ui/index.html", "text/html; charset=utf-8"},
+		{"/ui/index.html", "ui/index.html", "text/html; charset=utf-8"},
+		{"/ui/app.js", "ui/app.js", "javascript"},
+		{"/ui/style.css", "ui/style.css", "text/css; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			expected, err := ui.ReadFile(tt.asset)
+			if err != nil {
+				t.Fatalf("ui.ReadFile() error = %v", err)
+			}
+
+			resp, body := get(t, ts.URL+tt.path)
+			if resp.StatusCode != http.StatusOK || !strings.Contains(resp.Header.Get("Content-Type"), tt.contentType) || body != string(expected) {
+				t.Errorf("expected=[%v %v] <> actual[%v %v]\n", http.StatusOK, tt.contentType, resp.StatusCode, resp.Header.Get("Content-Type"))
+			}
+		})
+	}
+
+	for _, path := range []string{"/unknown", "/ui/unknown.js"} {
+		if resp, _ := get(t, ts.URL+path); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%v: expected=[%v] <> actual[%v]\n", path, http.StatusNotFound, resp.StatusCode)
+		}
+	}
+}
+
+func TestReportHandler(t *testing.T) {
+	_, ts := setupFakeJira(t, nil)
+
+	expected := "表示名,メールアドレス,アカウントID,消費時間,所定時間,差分\n" +
+		"Alice,alice@example.com,5b10a2844c20165700ede21g,11.00,160.00,-149.00\n" +
+		"Bob,bob@example.com,5b10ac8d82e05b22cc7d4ef5,8.00,160.00,-152.00\n"
+	for _, path := range []string{"/report?report=timesheet", "/?report=timesheet"} {
+		resp, body := get(t, ts.URL+path)
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/csv" || body != expected {
+			t.Errorf("%v: expected=[%v] <> actual[%v %v]\n", path, expected, resp.StatusCode, body)
+		}
+	}
+
+	if resp, body := get(t, ts.URL+"/report?baseurl=https://attacker.example.com"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected=[%v] <> actual[%v %v]\n", http.StatusBadRequest, resp.StatusCode, body)
+	}
+
+	resp, body := get(t, ts.URL+"/report")
+	expected = "キー,概要,消費時間\n" +
+		"DEMO-1,ログイン画面の作成,16.00\n" +
+		"DEMO-2,ログイン画面の単体テスト,4.00\n" +
+		"DEMO-3,パスワード再設定でエラーになる,3.00\n"
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/csv" || body != expected {
+		t.Errorf("expected=[%v] <> actual[%v %v]\n", expected, resp.StatusCode, body)
+	}
+}
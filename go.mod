@@ -1,3 +1,8 @@
 module bitbucket.org/yujiorama/jira-timespent-report
 
 go 1.16
+
+require (
+	gopkg.in/yaml.v3 v3.0.1
+	modernc.org/sqlite v1.14.6
+)
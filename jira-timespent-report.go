@@ -15,6 +15,5 @@ func main() {
 		os.Exit(0)
 	}
 
-	cli.Do()
-	os.Exit(0)
+	os.Exit(cli.Do())
 }
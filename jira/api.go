@@ -0,0 +1,543 @@
+package jira
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+var apiReports = []string{defaultReport, "status", "timesheet", "compliance", "cost", "team"}
+
+type ApiSearchRequest struct {
+	Query           string            `json:"query,omitempty"`
+	Filter          string            `json:"filter,omitempty"`
+	NamedQueries    map[string]string `json:"namedQueries,omitempty"`
+	NamedFilters    map[string]string `json:"namedFilters,omitempty"`
+	Projects        []string          `json:"projects,omitempty"`
+	Assignees       []string          `json:"assignees,omitempty"`
+	Statuses        []string          `json:"statuses,omitempty"`
+	IssueTypes      []string          `json:"issueTypes,omitempty"`
+	Labels          []string          `json:"labels,omitempty"`
+	WorklogAuthors  []string          `json:"worklogAuthors,omitempty"`
+	MaxResult       *int              `json:"maxResult,omitempty"`
+	SearchApi       string            `json:"searchApi,omitempty"`
+	TargetYearMonth string            `json:"targetYearMonth,omitempty"`
+	FromDb          bool              `json:"fromDb,omitempty"`
+	Snapshot        *int              `json:"snapshot,omitempty"`
+}
+
+type ApiReportRequest struct {
+	ApiSearchRequest
+	Report         string   `json:"report,omitempty"`
+	Worklog        bool     `json:"worklog,omitempty"`
+	Fields         []string `json:"fields,omitempty"`
+	TimeUnit       string   `json:"timeUnit,omitempty"`
+	HoursPerDay    *int     `json:"hoursPerDay,omitempty"`
+	DaysPerMonth   *int     `json:"daysPerMonth,omitempty"`
+	MinHours       *float64 `json:"minHours,omitempty"`
+	MaxHours       *float64 `json:"maxHours,omitempty"`
+	InvoiceBy      string   `json:"invoiceBy,omitempty"`
+	TeamGroups     []string `json:"teamGroups,omitempty"`
+	TeamAllocation string   `json:"teamAllocation,omitempty"`
+	RoundMode      string   `json:"roundMode,omitempty"`
+	RoundMethod    string   `json:"roundMethod,omitempty"`
+	RoundIncrement *float64 `json:"roundIncrement,omitempty"`
+	Precision      *int     `json:"precision,omitempty"`
+}
+
+type ApiError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type ApiErrors []ApiError
+
+type ApiErrorResponse struct {
+	Errors ApiErrors `json:"errors"`
+}
+
+type ApiIssuesResponse struct {
+	TargetYearMonth string `json:"targetYearMonth"`
+	Issues          Issues `json:"issues"`
+}
+
+type ApiWorklogsResponse struct {
+	TargetYearMonth string   `json:"targetYearMonth"`
+	Worklogs        Worklogs `json:"worklogs"`
+}
+
+type ApiReportResponse struct {
+	Report          string `json:"report"`
+	TargetYearMonth string `json:"targetYearMonth"`
+	TimeUnit        string `json:"timeUnit"`
+	Tables          Tables `json:"tables"`
+}
+
+func (e ApiError) Error() string {
+
+	if len(e.Field) > 0 {
+		return fmt.Sprintf("%s: %s", e.Field, e.Message)
+	}
+
+	return e.Message
+}
+
+func (errs ApiErrors) Error() string {
+
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+func NewApiErrorResponse(errs []error) *ApiErrorResponse {
+
+	response := &ApiErrorResponse{Errors: ApiErrors{}}
+	for _, err := range errs {
+		var apiErrors ApiErrors
+		if errors.As(err, &apiErrors) {
+			response.Errors = append(response.Errors, apiErrors...)
+			continue
+		}
+		response.Errors = append(response.Errors, ApiError{Message: err.Error()})
+	}
+
+	return response
+}
+
+func apiFields(t reflect.Type, fields map[string]bool) map[string]bool {
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			apiFields(field.Type, fields)
+			continue
+		}
+		if name := strings.Split(field.Tag.Get("json"), ",")[0]; len(name) > 0 {
+			fields[name] = true
+		}
+	}
+
+	return fields
+}
+
+func decodeApiRequest(r io.Reader, v interface{}) error {
+
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return ApiErrors{{Message: fmt.Sprintf("request body can not be read: %v", err)}}
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return ApiErrors{{Message: "empty request body"}}
+	}
+
+	var object map[string]json.RawMessage
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if err := decoder.Decode(&object); err != nil {
+		var syntaxError *json.SyntaxError
+		switch {
+		case err == io.ErrUnexpectedEOF:
+			return ApiErrors{{Message: "malformed json: unexpected end of request body"}}
+		case errors.As(err, &syntaxError):
+			return ApiErrors{{Message: fmt.Sprintf("malformed json at offset %d: %v", syntaxError.Offset, syntaxError)}}
+		}
+		return ApiErrors{{Message: "request body must be a json object"}}
+	}
+	if decoder.More() {
+		return ApiErrors{{Message: "request body must contain a single json object"}}
+	}
+
+	errs := make(ApiErrors, 0)
+	known := apiFields(reflect.TypeOf(v).Elem(), map[string]bool{})
+	for name := range object {
+		if !known[name] {
+			errs = append(errs, ApiError{Field: name, Message: "unknown field"})
+		}
+	}
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool {
+			return errs[i].Field < errs[j].Field
+		})
+		return errs
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		var typeError *json.UnmarshalTypeError
+		if errors.As(err, &typeError) {
+			return ApiErrors{{Field: typeError.Field, Message: fmt.Sprintf("must be %v", typeError.Type)}}
+		}
+		return ApiErrors{{Message: err.Error()}}
+	}
+
+	return nil
+}
+
+func DecodeApiSearchRequest(r io.Reader) (*ApiSearchRequest, error) {
+
+	var req ApiSearchRequest
+	if err := decodeApiRequest(r, &req); err != nil {
+		return nil, err
+	}
+
+	if errs := req.validate(); len(errs) > 0 {
+		return nil, errs
+	}
+
+	return &req, nil
+}
+
+func DecodeApiReportRequest(r io.Reader) (*ApiReportRequest, error) {
+
+	var req ApiReportRequest
+	if err := decodeApiRequest(r, &req); err != nil {
+		return nil, err
+	}
+
+	if errs := req.validate(); len(errs) > 0 {
+		return nil, errs
+	}
+
+	return &req, nil
+}
+
+func validateList(field string, values []string) ApiErrors {
+
+	errs := make(ApiErrors, 0)
+	for i, value := range values {
+		if len(strings.TrimSpace(value)) == 0 {
+			errs = append(errs, ApiError{Field: fmt.Sprintf("%s[%d]", field, i), Message: "must not be empty"})
+		} else if strings.Contains(value, ",") {
+			errs = append(errs, ApiError{Field: fmt.Sprintf("%s[%d]", field, i), Message: "must not contain a comma"})
+		}
+	}
+
+	return errs
+}
+
+func validateChoice(field string, value string, choices ...string) ApiErrors {
+
+	if len(value) == 0 {
+		return nil
+	}
+
+	for _, choice := range choices {
+		if value == choice {
+			return nil
+		}
+	}
+
+	return ApiErrors{{Field: field, Message: fmt.Sprintf("must be one of %s", strings.Join(choices, ", "))}}
+}
+
+func (req *ApiSearchRequest) validate() ApiErrors {
+
+	errs := make(ApiErrors, 0)
+
+	for _, name := range sortedKeys(req.NamedQueries) {
+		if len(name) == 0 || strings.ContainsAny(name, " \t=") {
+			errs = append(errs, ApiError{Field: "namedQueries", Message: fmt.Sprintf("invalid query name: %q", name)})
+		} else if len(strings.TrimSpace(req.NamedQueries[name])) == 0 {
+			errs = append(errs, ApiError{Field: "namedQueries." + name, Message: "must not be empty"})
+		}
+	}
+	for _, name := range sortedKeys(req.NamedFilters) {
+		if len(name) == 0 || strings.ContainsAny(name, " \t=") {
+			errs = append(errs, ApiError{Field: "namedFilters", Message: fmt.Sprintf("invalid filter name: %q", name)})
+		} else if !isDigits(req.NamedFilters[name]) {
+			errs = append(errs, ApiError{Field: "namedFilters." + name, Message: "must be a filter id"})
+		}
+	}
+	if len(req.Filter) > 0 && !isDigits(req.Filter) {
+		errs = append(errs, ApiError{Field: "filter", Message: "must be a filter id"})
+	}
+
+	errs = append(errs, validateList("projects", req.Projects)...)
+	errs = append(errs, validateList("assignees", req.Assignees)...)
+	errs = append(errs, validateList("statuses", req.Statuses)...)
+	errs = append(errs, validateList("issueTypes", req.IssueTypes)...)
+	errs = append(errs, validateList("labels", req.Labels)...)
+	errs = append(errs, validateList("worklogAuthors", req.WorklogAuthors)...)
+
+	if req.MaxResult != nil && *req.MaxResult <= 0 {
+		errs = append(errs, ApiError{Field: "maxResult", Message: "must be greater than 0"})
+	}
+	errs = append(errs, validateChoice("searchApi", req.SearchApi, searchApiAuto, searchApiJql, searchApiLegacy)...)
+
+	if len(req.TargetYearMonth) > 0 {
+		if _, err := time.Parse("2006-01", req.TargetYearMonth); err != nil {
+			errs = append(errs, ApiError{Field: "targetYearMonth", Message: "must be yyyy-MM"})
+		}
+	}
+
+	if req.Snapshot != nil {
+		if !req.FromDb {
+			errs = append(errs, ApiError{Field: "snapshot", Message: "can only be used with fromDb"})
+		} else if *req.Snapshot < 0 {
+			errs = append(errs, ApiError{Field: "snapshot", Message: "must not be negative"})
+		}
+	}
+
+	return errs
+}
+
+func (req *ApiReportRequest) validate() ApiErrors {
+
+	errs := req.ApiSearchRequest.validate()
+
+	errs = append(errs, validateChoice("report", req.Report, apiReports...)...)
+	errs = append(errs, validateList("fields", req.Fields)...)
+	errs = append(errs, validateChoice("timeUnit", strings.ToLower(req.TimeUnit), "d", "dd", "h", "hh", "m", "mm")...)
+
+	if req.HoursPerDay != nil && *req.HoursPerDay <= 0 {
+		errs = append(errs, ApiError{Field: "hoursPerDay", Message: "must be greater than 0"})
+	}
+	if req.DaysPerMonth != nil && *req.DaysPerMonth < 0 {
+		errs = append(errs, ApiError{Field: "daysPerMonth", Message: "must not be negative"})
+	}
+	if req.MinHours != nil && *req.MinHours < 0 {
+		errs = append(errs, ApiError{Field: "minHours", Message: "must not be negative"})
+	}
+	if req.MaxHours != nil && *req.MaxHours < 0 {
+		errs = append(errs, ApiError{Field: "maxHours", Message: "must not be negative"})
+	}
+
+	errs = append(errs, validateChoice("invoiceBy", req.InvoiceBy, "project", "epic")...)
+	errs = append(errs, validateList("teamGroups", req.TeamGroups)...)
+	errs = append(errs, validateChoice("teamAllocation", req.TeamAllocation, allocationFirst, allocationSplit, allocationAll)...)
+	errs = append(errs, validateChoice("roundMode", req.RoundMode, roundEntry, roundTotal)...)
+	errs = append(errs, validateChoice("roundMethod", strings.ToLower(req.RoundMethod), roundNearest, roundUp, roundDown)...)
+
+	if req.RoundIncrement != nil && *req.RoundIncrement < 0 {
+		errs = append(errs, ApiError{Field: "roundIncrement", Message: "must not be negative"})
+	}
+	if req.Precision != nil && *req.Precision < 0 {
+		errs = append(errs, ApiError{Field: "precision", Message: "must not be negative"})
+	}
+
+	return errs
+}
+
+func sortedKeys(m map[string]string) []string {
+
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func isDigits(value string) bool {
+
+	if len(value) == 0 {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (req *ApiSearchRequest) apply(c *Config) error {
+
+	if len(req.Query) > 0 {
+		c.Query = req.Query
+	}
+	if len(req.Filter) > 0 {
+		c.Filter = req.Filter
+	}
+
+	queries := make(NamedQueries, 0, len(req.NamedQueries)+len(req.NamedFilters))
+	for name, query := range req.NamedQueries {
+		queries = append(queries, NamedQuery{Name: name, Query: strings.TrimSpace(query)})
+	}
+	for name, filter := range req.NamedFilters {
+		queries = append(queries, NamedQuery{Name: name, Filter: filter})
+	}
+	if len(queries) > 0 {
+		sort.SliceStable(queries, func(i, j int) bool {
+			return queries[i].Name < queries[j].Name
+		})
+		c.Queries = queries
+	}
+
+	if len(req.Projects) > 0 {
+		c.Projects = strings.Join(req.Projects, ",")
+	}
+	if len(req.Assignees) > 0 {
+		c.Assignees = strings.Join(req.Assignees, ",")
+	}
+	if len(req.Statuses) > 0 {
+		c.Statuses = strings.Join(req.Statuses, ",")
+	}
+	if len(req.IssueTypes) > 0 {
+		c.IssueTypes = strings.Join(req.IssueTypes, ",")
+	}
+	if len(req.Labels) > 0 {
+		c.Labels = strings.Join(req.Labels, ",")
+	}
+	if len(req.WorklogAuthors) > 0 {
+		c.WorklogAuthors = strings.Join(req.WorklogAuthors, ",")
+	}
+	if req.MaxResult != nil {
+		c.MaxResult = *req.MaxResult
+	}
+	if len(req.SearchApi) > 0 {
+		c.SearchApi = req.SearchApi
+	}
+	if len(req.TargetYearMonth) > 0 {
+		c.TargetYearMonth = req.TargetYearMonth
+	}
+	c.FromDb = req.FromDb
+	if req.Snapshot != nil {
+		c.Snapshot = *req.Snapshot
+	}
+	c.DryRun = false
+
+	return nil
+}
+
+func (req *ApiReportRequest) apply(c *Config) error {
+
+	if err := req.ApiSearchRequest.apply(c); err != nil {
+		return err
+	}
+
+	c.Report = defaultReport
+	if len(req.Report) > 0 {
+		c.Report = req.Report
+	}
+	c.Worklog = req.Worklog
+	if len(req.Fields) > 0 {
+		c.FieldNames = strings.Join(req.Fields, ",")
+	}
+	if len(req.TimeUnit) > 0 {
+		c.TimeUnit = req.TimeUnit
+	}
+	if req.HoursPerDay != nil {
+		c.HoursPerDay = *req.HoursPerDay
+	}
+	if req.DaysPerMonth != nil {
+		c.DaysPerMonth = *req.DaysPerMonth
+	}
+	if req.MinHours != nil {
+		c.MinHours = *req.MinHours
+	}
+	if req.MaxHours != nil {
+		c.MaxHours = *req.MaxHours
+	}
+	if len(req.InvoiceBy) > 0 {
+		c.InvoiceBy = req.InvoiceBy
+	}
+	if len(req.TeamGroups) > 0 {
+		c.TeamGroups = strings.Join(req.TeamGroups, ",")
+	}
+	if len(req.TeamAllocation) > 0 {
+		c.TeamAllocation = req.TeamAllocation
+	}
+	if len(req.RoundMode) > 0 {
+		c.RoundMode = req.RoundMode
+	}
+	if len(req.RoundMethod) > 0 {
+		c.RoundMethod = req.RoundMethod
+	}
+	if req.RoundIncrement != nil {
+		c.RoundIncrement = *req.RoundIncrement
+	}
+	if req.Precision != nil {
+		c.Precision = *req.Precision
+	}
+
+	return nil
+}
+
+func targetYearMonth() string {
+
+	t, err := config.TargetMonth()
+	if err != nil {
+		return config.TargetYearMonth
+	}
+
+	return t.Format("2006-01")
+}
+
+func SearchIssues(req *ApiSearchRequest) (*ApiIssuesResponse, []error) {
+
+	var response *ApiIssuesResponse
+	var searchErrors []error
+	err := withConfig(req.apply, func() {
+		config.Report = reportJson
+		config.Worklog = false
+
+		var issues IssueSearchResults
+		issues, _, searchErrors = Search()
+		if len(searchErrors) > 0 {
+			return
+		}
+		response = &ApiIssuesResponse{TargetYearMonth: targetYearMonth(), Issues: NewExport(issues, nil).Issues}
+	})
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	return response, searchErrors
+}
+
+func SearchWorklogs(req *ApiSearchRequest) (*ApiWorklogsResponse, []error) {
+
+	var response *ApiWorklogsResponse
+	var searchErrors []error
+	err := withConfig(req.apply, func() {
+		config.Report = reportJson
+		config.Worklog = true
+
+		var worklogs WorklogResults
+		_, worklogs, searchErrors = Search()
+		if len(searchErrors) > 0 {
+			return
+		}
+		response = &ApiWorklogsResponse{TargetYearMonth: targetYearMonth(), Worklogs: NewExport(nil, worklogs).Worklogs}
+	})
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	return response, searchErrors
+}
+
+func SearchReport(req *ApiReportRequest) (*ApiReportResponse, []error) {
+
+	var response *ApiReportResponse
+	var reportErrors []error
+	err := withConfig(req.apply, func() {
+		issues, worklogs, searchErrors := Search()
+		if len(searchErrors) > 0 {
+			reportErrors = searchErrors
+			return
+		}
+
+		var tables Tables
+		if tables, reportErrors = ReportTables(issues, worklogs); len(reportErrors) > 0 {
+			return
+		}
+		response = &ApiReportResponse{Report: config.Report, TargetYearMonth: targetYearMonth(), TimeUnit: config.TimeUnit, Tables: tables}
+	})
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	return response, reportErrors
+}
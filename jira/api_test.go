@@ -0,0 +1,190 @@
+package jira
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfig_SetQueryParams(t *testing.T) {
+	tests := []struct {
+		name      string
+		params    url.Values
+		maxResult int
+		worklog   bool
+		wantErr   string
+	}{
+		{
+			name:      "valid",
+			params:    url.Values{"maxresult": {"10"}, "worklog": {"true"}},
+			maxResult: 10,
+			worklog:   true,
+		},
+		{
+			name:      "invalid int keeps the value",
+			params:    url.Values{"maxresult": {"ten"}, "worklog": {"true"}},
+			maxResult: 50,
+			worklog:   true,
+			wantErr:   "invalid query parameter: maxresult=[ten]",
+		},
+		{
+			name:      "invalid values are reported together",
+			params:    url.Values{"maxresult": {"1.5"}, "worklog": {"yes"}, "minhours": {"x"}},
+			maxResult: 50,
+			wantErr:   "invalid query parameter: maxresult=[1.5], minhours=[x], worklog=[yes]",
+		},
+		{
+			name:      "invalid named query",
+			params:    url.Values{"namedquery": {"alpha=project = ALPHA", "project = BETA"}},
+			maxResult: 50,
+			wantErr:   "invalid query parameter: namedquery=[project = BETA]",
+		},
+		{
+			name:      "base url is not accepted",
+			params:    url.Values{"baseurl": {"https://attacker.example.com"}},
+			maxResult: 50,
+			wantErr:   "invalid query parameter: baseurl=[https://attacker.example.com]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Config{MaxResult: defaultMaxResult}
+			err := c.SetQueryParams(tt.params)
+			if (err != nil) != (len(tt.wantErr) > 0) || (err != nil && err.Error() != tt.wantErr) {
+				t.Errorf("expected=[%v] <> actual[%v]\n", tt.wantErr, err)
+			}
+			if c.MaxResult != tt.maxResult || c.Worklog != tt.worklog {
+				t.Errorf("expected=[%v %v] <> actual[%v %v]\n", tt.maxResult, tt.worklog, c.MaxResult, c.Worklog)
+			}
+		})
+	}
+}
+
+func TestDecodeApiReportRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected ApiErrors
+	}{
+		{
+			name: "valid",
+			body: `{"targetYearMonth":"2020-08","report":"timesheet","projects":["DEMO"],"maxResult":10,"roundIncrement":0.25}`,
+		},
+		{
+			name:     "empty body",
+			body:     ``,
+			expected: ApiErrors{{Message: "empty request body"}},
+		},
+		{
+			name:     "unknown field",
+			body:     `{"maxresult":10}`,
+			expected: ApiErrors{{Field: "maxresult", Message: "unknown field"}},
+		},
+		{
+			name:     "base url is not accepted",
+			body:     `{"baseUrl":"https://attacker.example.com"}`,
+			expected: ApiErrors{{Field: "baseUrl", Message: "unknown field"}},
+		},
+		{
+			name:     "wrong type",
+			body:     `{"maxResult":"10"}`,
+			expected: ApiErrors{{Field: "maxResult", Message: "must be int"}},
+		},
+		{
+			name:     "trailing data",
+			body:     `{} {}`,
+			expected: ApiErrors{{Message: "request body must contain a single json object"}},
+		},
+		{
+			name: "invalid values",
+			body: `{"targetYearMonth":"2020-8","maxResult":0,"projects":["A,B",""],"report":"json","timeUnit":"ss","precision":-1}`,
+			expected: ApiErrors{
+				{Field: "projects[0]", Message: "must not contain a comma"},
+				{Field: "projects[1]", Message: "must not be empty"},
+				{Field: "maxResult", Message: "must be greater than 0"},
+				{Field: "targetYearMonth", Message: "must be yyyy-MM"},
+				{Field: "report", Message: "must be one of timespent, status, timesheet, compliance, cost, team"},
+				{Field: "timeUnit", Message: "must be one of d, dd, h, hh, m, mm"},
+				{Field: "precision", Message: "must not be negative"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeApiReportRequest(strings.NewReader(tt.body))
+			var actual ApiErrors
+			if err != nil {
+				actual = NewApiErrorResponse([]error{err}).Errors
+			}
+			if !reflect.DeepEqual(tt.expected, actual) {
+				t.Errorf("expected=[%v] <> actual[%v]\n", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSearchReport(t *testing.T) {
+	setupFakeJira(t)
+
+	req, err := DecodeApiReportRequest(strings.NewReader(`{"report":"timesheet","timeUnit":"h"}`))
+	if err != nil {
+		t.Fatalf("DecodeApiReportRequest error = %v", err)
+	}
+
+	response, reportErrors := SearchReport(req)
+	if len(reportErrors) > 0 {
+		t.Fatalf("SearchReport error = %v", reportErrors)
+	}
+	if response.Report != "timesheet" || response.TargetYearMonth != "2020-08" || len(response.Tables) != 1 {
+		t.Fatalf("expected=[%v] <> actual[%v]\n", "timesheet 2020-08 1 table", response)
+	}
+
+	expected := []string{"表示名", "メールアドレス", "アカウントID", "消費時間", "所定時間", "差分"}
+	if !reflect.DeepEqual(expected, response.Tables[0].Columns) {
+		t.Errorf("expected=[%v] <> actual[%v]\n", expected, response.Tables[0].Columns)
+	}
+	if len(response.Tables[0].Rows) != 2 {
+		t.Errorf("expected=[%v] <> actual[%v]\n", 2, response.Tables[0].Rows)
+	}
+	req, err = DecodeApiReportRequest(strings.NewReader(`{"worklog":true}`))
+	if err != nil {
+		t.Fatalf("DecodeApiReportRequest error = %v", err)
+	}
+	response, reportErrors = SearchReport(req)
+	if len(reportErrors) > 0 {
+		t.Fatalf("SearchReport error = %v", reportErrors)
+	}
+	if len(response.Tables) != 2 || len(response.Tables[0].Rows) != 3 || len(response.Tables[1].Rows) != 4 {
+		t.Errorf("expected=[%v] <> actual[%v]\n", "3 issues and 4 worklogs", response.Tables)
+	}
+
+	if config.Report != defaultReport || config.TimeUnit != "hh" {
+		t.Errorf("expected=[%v] <> actual[%v]\n", "restored config", config)
+	}
+}
+
+func TestSearchIssuesAndWorklogs(t *testing.T) {
+	setupFakeJira(t)
+
+	req, err := DecodeApiSearchRequest(strings.NewReader(`{"targetYearMonth":"2020-08"}`))
+	if err != nil {
+		t.Fatalf("DecodeApiSearchRequest error = %v", err)
+	}
+
+	issues, searchErrors := SearchIssues(req)
+	if len(searchErrors) > 0 {
+		t.Fatalf("SearchIssues error = %v", searchErrors)
+	}
+	if len(issues.Issues) != 3 || issues.Issues[0].Fields.Status.Name == "" {
+		t.Errorf("expected=[%v] <> actual[%v]\n", "3 issues with status", issues.Issues)
+	}
+
+	worklogs, searchErrors := SearchWorklogs(req)
+	if len(searchErrors) > 0 {
+		t.Fatalf("SearchWorklogs error = %v", searchErrors)
+	}
+	if len(worklogs.Worklogs) != 4 {
+		t.Errorf("expected=[%v] <> actual[%v]\n", 4, len(worklogs.Worklogs))
+	}
+}
@@ -26,3 +26,14 @@ func (c *Cache) get(k string) (interface{}, bool) {
 	v, ok := c.memo[k]
 	return v, ok
 }
+
+func (c *Cache) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.memo = map[string]interface{}{}
+}
+
+func ClearCache() {
+
+	cache.clear()
+}
@@ -0,0 +1,271 @@
+package jira
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Calendar struct {
+	mutex    *sync.Mutex
+	weekends map[time.Weekday]bool
+	holidays map[string]string
+	builtin  map[int]bool
+}
+
+const dateLayout = "2006-01-02"
+
+func NewCalendar() *Calendar {
+
+	return &Calendar{
+		mutex: &sync.Mutex{},
+		weekends: map[time.Weekday]bool{
+			time.Saturday: true,
+			time.Sunday:   true,
+		},
+		holidays: map[string]string{},
+		builtin:  map[int]bool{},
+	}
+}
+
+func (c *Calendar) AddHoliday(date time.Time, name string) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.holidays[date.Format(dateLayout)] = name
+}
+
+func (c *Calendar) LoadHolidays(r io.Reader) error {
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		value, name := line, ""
+		if i := strings.IndexAny(line, ", \t"); i >= 0 {
+			value, name = line[:i], strings.TrimSpace(strings.Trim(line[i:], ", \t"))
+		}
+
+		date, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return fmt.Errorf("time.Parse error: %v\nlineNumber=[%v],line=[%v]", err, lineNumber, line)
+		}
+		c.AddHoliday(date, name)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scanner.Err error: %v", err)
+	}
+
+	return nil
+}
+
+func (c *Calendar) Holiday(date time.Time) (string, bool) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.builtin[date.Year()] {
+		for key, name := range japaneseHolidays(date.Year()) {
+			if _, ok := c.holidays[key]; !ok {
+				c.holidays[key] = name
+			}
+		}
+		c.builtin[date.Year()] = true
+	}
+
+	name, ok := c.holidays[date.Format(dateLayout)]
+	return name, ok
+}
+
+func (c *Calendar) IsWorkingDay(date time.Time) bool {
+
+	if c.weekends[date.Weekday()] {
+		return false
+	}
+
+	_, ok := c.Holiday(date)
+	return !ok
+}
+
+func (c *Calendar) WorkingDaysOf(month time.Time) []time.Time {
+
+	days := make([]time.Time, 0, 31)
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+	for day := first; day.Month() == first.Month(); day = day.AddDate(0, 0, 1) {
+		if c.IsWorkingDay(day) {
+			days = append(days, day)
+		}
+	}
+
+	return days
+}
+
+func (c *Calendar) WorkingDays(month time.Time) int {
+
+	return len(c.WorkingDaysOf(month))
+}
+
+func nthWeekday(year int, month time.Month, n int, weekday time.Weekday) time.Time {
+
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(weekday) - int(first.Weekday()) + 7) % 7
+	return first.AddDate(0, 0, offset+(n-1)*7)
+}
+
+func japaneseHolidays(year int) map[string]string {
+
+	holidays := map[string]string{}
+	add := func(date time.Time, name string) {
+		holidays[date.Format(dateLayout)] = name
+	}
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	add(date(time.January, 1), "元日")
+	add(nthWeekday(year, time.January, 2, time.Monday), "成人の日")
+	add(date(time.February, 11), "建国記念の日")
+	if year >= 2020 {
+		add(date(time.February, 23), "天皇誕生日")
+	} else if year <= 2018 {
+		add(date(time.December, 23), "天皇誕生日")
+	}
+	add(date(time.March, int(20.8431+0.242194*float64(year-1980))-(year-1980)/4), "春分の日")
+	add(date(time.April, 29), "昭和の日")
+	add(date(time.May, 3), "憲法記念日")
+	add(date(time.May, 4), "みどりの日")
+	add(date(time.May, 5), "こどもの日")
+	add(nthWeekday(year, time.September, 3, time.Monday), "敬老の日")
+	add(date(time.September, int(23.2488+0.242194*float64(year-1980))-(year-1980)/4), "秋分の日")
+	add(date(time.November, 3), "文化の日")
+	add(date(time.November, 23), "勤労感謝の日")
+
+	switch year {
+	case 2020:
+		add(date(time.July, 23), "海の日")
+		add(date(time.July, 24), "スポーツの日")
+		add(date(time.August, 10), "山の日")
+	case 2021:
+		add(date(time.July, 22), "海の日")
+		add(date(time.July, 23), "スポーツの日")
+		add(date(time.August, 8), "山の日")
+	default:
+		add(nthWeekday(year, time.July, 3, time.Monday), "海の日")
+		if year >= 2016 {
+			add(date(time.August, 11), "山の日")
+		}
+		if year >= 2020 {
+			add(nthWeekday(year, time.October, 2, time.Monday), "スポーツの日")
+		} else {
+			add(nthWeekday(year, time.October, 2, time.Monday), "体育の日")
+		}
+	}
+
+	if year == 2019 {
+		add(date(time.April, 30), "国民の休日")
+		add(date(time.May, 1), "即位の日")
+		add(date(time.May, 2), "国民の休日")
+		add(date(time.October, 22), "即位礼正殿の儀")
+	}
+
+	for day := date(time.January, 2); day.Year() == year; day = day.AddDate(0, 0, 1) {
+		key := day.Format(dateLayout)
+		if _, ok := holidays[key]; ok {
+			continue
+		}
+		_, before := holidays[day.AddDate(0, 0, -1).Format(dateLayout)]
+		_, after := holidays[day.AddDate(0, 0, 1).Format(dateLayout)]
+		if before && after && day.Weekday() != time.Sunday {
+			holidays[key] = "国民の休日"
+		}
+	}
+
+	substitutes := map[string]string{}
+	for key := range holidays {
+		day, _ := time.Parse(dateLayout, key)
+		if day.Weekday() != time.Sunday {
+			continue
+		}
+		for day = day.AddDate(0, 0, 1); ; day = day.AddDate(0, 0, 1) {
+			if _, ok := holidays[day.Format(dateLayout)]; !ok {
+				substitutes[day.Format(dateLayout)] = "振替休日"
+				break
+			}
+		}
+	}
+	for key, name := range substitutes {
+		holidays[key] = name
+	}
+
+	return holidays
+}
+
+func (c *Config) calendar() (*Calendar, error) {
+
+	cacheKey := fmt.Sprintf("calendar_%s", c.Holidays)
+	if v, ok := cache.get(cacheKey); ok {
+		return v.(*Calendar), nil
+	}
+
+	calendar := NewCalendar()
+	if len(c.Holidays) > 0 {
+		if err := calendar.loadHolidaysFile(c.Holidays); err != nil {
+			return nil, fmt.Errorf("calendar.loadHolidaysFile error: %v\nHolidays=[%v]", err, c.Holidays)
+		}
+	}
+
+	cache.put(cacheKey, calendar)
+	return calendar, nil
+}
+
+func (c *Calendar) loadHolidaysFile(name string) error {
+
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("os.Open error: %v\nname=[%v]", err, name)
+	}
+	defer f.Close()
+
+	return c.LoadHolidays(f)
+}
+
+func (c *Config) workingDaysPerMonth() (int, error) {
+
+	if c.DaysPerMonth > 0 {
+		return c.DaysPerMonth, nil
+	}
+
+	return c.workingDaysOfTargetMonth()
+}
+
+func (c *Config) workingDaysOfTargetMonth() (int, error) {
+
+	calendar, err := c.calendar()
+	if err != nil {
+		return 0, err
+	}
+
+	t, err := c.TargetMonth()
+	if err != nil {
+		return calendar.WorkingDays(c.clock()), nil
+	}
+
+	return calendar.WorkingDays(*t), nil
+}
+
+func (c *Config) ExpectedSeconds() (int, error) {
+
+	days, err := c.workingDaysOfTargetMonth()
+	if err != nil {
+		return 0, err
+	}
+
+	return 60 * 60 * c.HoursPerDay * days, nil
+}
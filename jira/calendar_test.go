@@ -0,0 +1,84 @@
+package jira
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendar_Holiday(t *testing.T) {
+	calendar := NewCalendar()
+
+	tests := []struct {
+		date string
+		want string
+	}{
+		{date: "2019-04-30", want: "国民の休日"},
+		{date: "2019-05-01", want: "即位の日"},
+		{date: "2019-05-02", want: "国民の休日"},
+		{date: "2019-05-06", want: "振替休日"},
+		{date: "2019-10-22", want: "即位礼正殿の儀"},
+		{date: "2020-08-10", want: "山の日"},
+		{date: "2021-07-23", want: "スポーツの日"},
+		{date: "2026-03-20", want: "春分の日"},
+		{date: "2026-05-06", want: "振替休日"},
+		{date: "2026-09-22", want: "国民の休日"},
+		{date: "2026-09-23", want: "秋分の日"},
+		{date: "2026-10-12", want: "スポーツの日"},
+		{date: "2026-10-13", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			date, _ := time.Parse(dateLayout, tt.date)
+			got, _ := calendar.Holiday(date)
+			if got != tt.want {
+				t.Errorf("Holiday() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalendar_WorkingDays(t *testing.T) {
+	calendar := NewCalendar()
+	month, _ := time.Parse(dateLayout, "2020-08-01")
+
+	if got := calendar.WorkingDays(month); got != 20 {
+		t.Errorf("WorkingDays() = %v, want %v", got, 20)
+	}
+
+	holidays := "# company holidays\n2020-08-12,夏季休暇\n2020-08-13 夏季休暇\n"
+	if err := calendar.LoadHolidays(strings.NewReader(holidays)); err != nil {
+		t.Fatalf("LoadHolidays() error = %v", err)
+	}
+
+	if got := calendar.WorkingDays(month); got != 18 {
+		t.Errorf("WorkingDays() = %v, want %v", got, 18)
+	}
+
+	if err := calendar.LoadHolidays(strings.NewReader("2020/08/14\n")); err == nil {
+		t.Errorf("LoadHolidays() error = nil, want error")
+	}
+}
+
+func TestConfig_calendar(t *testing.T) {
+	c := &Config{Holidays: "testdata/no-such-holidays.txt", HoursPerDay: 8, TargetYearMonth: "2020-08", clock: time.Now}
+
+	if _, err := c.calendar(); err == nil {
+		t.Errorf("calendar() error = nil, want error")
+	}
+	if _, err := c.calendar(); err == nil {
+		t.Errorf("calendar() error = nil, want error on second call")
+	}
+	if _, err := c.ExpectedSeconds(); err == nil {
+		t.Errorf("ExpectedSeconds() error = nil, want error")
+	}
+
+	c.Holidays = ""
+	c.DaysPerMonth = 24
+	if got, err := c.ExpectedSeconds(); err != nil || got != 20*8*60*60 {
+		t.Errorf("ExpectedSeconds() = %v, %v, want %v", got, err, 20*8*60*60)
+	}
+	if got, err := c.workingDaysPerMonth(); err != nil || got != 24 {
+		t.Errorf("workingDaysPerMonth() = %v, %v, want %v", got, err, 24)
+	}
+}
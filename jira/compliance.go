@@ -0,0 +1,258 @@
+package jira
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+type RosterMember struct {
+	Emailaddress string
+	Displayname  string
+}
+
+func (m *RosterMember) key() string {
+
+	if len(m.Emailaddress) > 0 {
+		return m.Emailaddress
+	}
+
+	return m.Displayname
+}
+
+type Roster []RosterMember
+
+const (
+	complianceMissing = "未入力"
+	complianceShort   = "不足"
+	complianceOver    = "超過"
+)
+
+type ComplianceDay struct {
+	Date      time.Time
+	Timespent TimeTotal
+	Kind      string
+}
+
+type AuthorCompliance struct {
+	Displayname  string
+	Emailaddress string
+	Timespent    TimeTotal
+	Expected     TimeTotal
+	Days         []ComplianceDay
+}
+
+func (a *AuthorCompliance) Shortfall() TimeTotal {
+
+	shortfall := a.Expected.Minus(a.Timespent)
+	if shortfall.Seconds() <= 0 {
+		return TimeTotal{}
+	}
+
+	return shortfall
+}
+
+func LoadRoster(r io.Reader) (Roster, error) {
+
+	roster := make(Roster, 0, 10)
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		values := strings.SplitN(line, ",", 2)
+		member := RosterMember{Emailaddress: strings.TrimSpace(values[0])}
+		if len(values) > 1 {
+			member.Displayname = strings.TrimSpace(values[1])
+		}
+		if len(member.key()) == 0 {
+			return nil, fmt.Errorf("empty member\nlineNumber=[%v],line=[%v]", lineNumber, line)
+		}
+		roster = append(roster, member)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanner.Err error: %v", err)
+	}
+
+	return roster, nil
+}
+
+func (c *Config) roster() (Roster, error) {
+
+	if len(c.Roster) == 0 {
+		return Roster{}, nil
+	}
+
+	f, err := os.Open(c.Roster)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open error: %v\nRoster=[%v]", err, c.Roster)
+	}
+	defer f.Close()
+
+	return LoadRoster(f)
+}
+
+func (c *Config) minSecondsPerDay() int {
+
+	if c.MinHours > 0 {
+		return int(c.MinHours * 60 * 60)
+	}
+
+	return 60 * 60 * c.HoursPerDay
+}
+
+func (c *Config) maxSecondsPerDay() int {
+
+	return int(c.MaxHours * 60 * 60)
+}
+
+func (worklogs Worklogs) Compliance(roster Roster) ([]AuthorCompliance, error) {
+
+	target, err := config.TargetMonth()
+	if err != nil {
+		return nil, fmt.Errorf("config.TargetMonth error: %v", err)
+	}
+
+	authors := map[string]*AuthorCompliance{}
+	daily := map[string]map[string]*TimeTotal{}
+	for _, member := range roster {
+		authors[member.key()] = &AuthorCompliance{
+			Displayname:  member.Displayname,
+			Emailaddress: member.Emailaddress,
+		}
+		daily[member.key()] = map[string]*TimeTotal{}
+	}
+
+	for _, worklog := range worklogs {
+		started, err := worklog.StartedTime()
+		if err != nil {
+			return nil, fmt.Errorf("worklog.StartedTime error: %v\nkey=[%v],started=[%v]", err, worklog.Key, worklog.Started)
+		}
+
+		key := worklog.authorKey()
+		for _, member := range roster {
+			if worklog.Author.matches(member.Emailaddress) || worklog.Author.matches(member.Displayname) {
+				key = member.key()
+				break
+			}
+		}
+
+		author, ok := authors[key]
+		if !ok {
+			author = &AuthorCompliance{}
+			authors[key] = author
+			daily[key] = map[string]*TimeTotal{}
+		}
+		if len(author.Displayname) == 0 {
+			author.Displayname = worklog.Author.Displayname
+		}
+		if len(author.Emailaddress) == 0 {
+			author.Emailaddress = worklog.Author.Emailaddress
+		}
+
+		author.Timespent.Add(worklog.Timespentseconds)
+
+		day, ok := daily[key][started.Format(dateLayout)]
+		if !ok {
+			day = &TimeTotal{}
+			daily[key][started.Format(dateLayout)] = day
+		}
+		day.Add(worklog.Timespentseconds)
+	}
+
+	calendar, err := config.calendar()
+	if err != nil {
+		return nil, err
+	}
+	minSeconds := config.minSecondsPerDay()
+	maxSeconds := config.maxSecondsPerDay()
+
+	first := time.Date(target.Year(), target.Month(), 1, 0, 0, 0, 0, time.Local)
+	expected := NewTimeTotal(60 * 60 * config.HoursPerDay * calendar.WorkingDays(first))
+	result := make([]AuthorCompliance, 0, len(authors))
+	for key, author := range authors {
+		author.Expected = expected
+		author.Days = make([]ComplianceDay, 0, 5)
+
+		for day := first; day.Month() == first.Month(); day = day.AddDate(0, 0, 1) {
+			timespent := TimeTotal{}
+			if total, ok := daily[key][day.Format(dateLayout)]; ok {
+				timespent = *total
+			}
+
+			kind := ""
+			switch {
+			case maxSeconds > 0 && timespent.Seconds() > maxSeconds:
+				kind = complianceOver
+			case !calendar.IsWorkingDay(day):
+			case timespent.Seconds() == 0:
+				kind = complianceMissing
+			case timespent.Seconds() < minSeconds:
+				kind = complianceShort
+			}
+
+			if len(kind) > 0 {
+				author.Days = append(author.Days, ComplianceDay{Date: day, Timespent: timespent, Kind: kind})
+			}
+		}
+
+		result = append(result, *author)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Emailaddress == result[j].Emailaddress {
+			return result[i].Displayname < result[j].Displayname
+		}
+		return result[i].Emailaddress < result[j].Emailaddress
+	})
+
+	return result, nil
+}
+
+func (results WorklogResults) ComplianceTables() (Tables, error) {
+
+	roster, err := config.roster()
+	if err != nil {
+		return nil, fmt.Errorf("config.roster error: %v", err)
+	}
+
+	compliance, err := results.inTargetMonth().Compliance(roster)
+	if err != nil {
+		return nil, fmt.Errorf("Compliance error: %v", err)
+	}
+
+	days := newTable("表示名", "メールアドレス", "日付", "消費時間", "判定")
+	for _, author := range compliance {
+		for _, day := range author.Days {
+			days.append([]string{
+				author.Displayname,
+				author.Emailaddress,
+				day.Date.Format(dateLayout),
+				day.Timespent.String(),
+				day.Kind,
+			})
+		}
+	}
+
+	authors := newTable("表示名", "メールアドレス", "消費時間", "所定時間", "不足時間")
+	for _, author := range compliance {
+		shortfall := author.Shortfall()
+		authors.append([]string{
+			author.Displayname,
+			author.Emailaddress,
+			author.Timespent.String(),
+			author.Expected.String(),
+			shortfall.String(),
+		})
+	}
+
+	return Tables{days, authors}, nil
+}
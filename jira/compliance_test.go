@@ -0,0 +1,61 @@
+package jira
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWorklogs_Compliance(t *testing.T) {
+	saved := *config
+	defer func() { *config = saved }()
+	config.TargetYearMonth = "2020-08"
+	config.HoursPerDay = 8
+	config.DaysPerMonth = 24
+	config.MinHours = 0
+	config.MaxHours = 10
+
+	roster, err := LoadRoster(strings.NewReader("# members\nalice@example.com,Alice\nbob@example.com,Bob\n"))
+	if err != nil {
+		t.Fatalf("LoadRoster() error = %v", err)
+	}
+
+	worklogs := make(Worklogs, 0, 25)
+	for _, day := range []string{"03", "04", "05", "06", "07", "11", "12", "13", "14", "17", "18", "19", "20", "21", "24", "25", "26", "27", "28", "31"} {
+		worklog := WorklogField{Key: "TEST-1", Started: "2020-08-" + day + "T09:00:00.000+0900", Timespentseconds: 8 * 60 * 60}
+		worklog.Author.Emailaddress = "alice@example.com"
+		worklogs = append(worklogs, worklog)
+	}
+	worklogs[0].Timespentseconds = 4 * 60 * 60
+	worklogs[1].Timespentseconds = 11 * 60 * 60
+	worklogs = worklogs[:len(worklogs)-1]
+
+	compliance, err := worklogs.Compliance(roster)
+	if err != nil {
+		t.Fatalf("Compliance() error = %v", err)
+	}
+	if len(compliance) != 2 {
+		t.Fatalf("expected=[2] <> actual=[%v]\n", len(compliance))
+	}
+
+	alice := compliance[0]
+	expected := []string{complianceShort, complianceOver, complianceMissing}
+	if len(alice.Days) != len(expected) {
+		t.Fatalf("expected=[%v] <> actual=[%v]\n", expected, alice.Days)
+	}
+	for i, kind := range expected {
+		if alice.Days[i].Kind != kind {
+			t.Errorf("expected=[%v] <> actual=[%v]\n", kind, alice.Days[i].Kind)
+		}
+	}
+	if alice.Expected.Seconds() != 20*8*60*60 {
+		t.Errorf("expected=[%v] <> actual=[%v]\n", 20*8*60*60, alice.Expected.Seconds())
+	}
+	if shortfall := alice.Shortfall(); shortfall.Seconds() != 9*60*60 {
+		t.Errorf("expected=[%v] <> actual=[%v]\n", 9*60*60, shortfall.Seconds())
+	}
+
+	bob := compliance[1]
+	if shortfall := bob.Shortfall(); bob.Displayname != "Bob" || len(bob.Days) != 20 || shortfall.Seconds() != 20*8*60*60 {
+		t.Errorf("unexpected compliance of bob: %v\n", bob)
+	}
+}
@@ -1,10 +1,9 @@
 package jira
 
 import (
-	"encoding/base64"
 	"fmt"
 	"net/url"
-	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -12,17 +11,65 @@ import (
 
 type Config struct {
 	BaseURL         string
+	Sites           string
+	AuthorMap       string
 	Query           string
 	Filter          string
+	Queries         NamedQueries
+	Projects        string
+	Assignees       string
+	Statuses        string
+	IssueTypes      string
+	Labels          string
+	WorklogAuthors  string
 	FieldNames      string
 	MaxResult       int
+	MaxRetry        int
 	ApiVersion      string
+	SearchApi       string
 	TimeUnit        string
 	HoursPerDay     int
 	DaysPerMonth    int
 	Worklog         bool
+	DryRun          bool
+	Demo            bool
+	Record          string
+	Replay          string
+	Command         string
+	Database        string
+	FromDb          bool
+	Snapshot        int
+	DiffFrom        string
+	DiffTo          string
+	Schedules       string
+	SmtpHost        string
+	SmtpFrom        string
+	SmtpStartTLS    bool
+	MailTo          string
+	MailSubject     string
+	SlackWebhook    string
+	TeamsWebhook    string
+	NotifyTemplate  string
+	NotifyLink      string
 	TargetYearMonth string
+	Report          string
+	Holidays        string
+	Roster          string
+	MinHours        float64
+	MaxHours        float64
+	RateCard        string
+	InvoiceBy       string
+	Users           string
+	Teams           string
+	TeamGroups      string
+	TeamAllocation  string
+	RoundMode       string
+	RoundMethod     string
+	RoundIncrement  float64
+	Precision       int
 	clock           func() time.Time
+	progress        *progress
+	worklogStream   *worklogStream
 }
 
 const (
@@ -31,16 +78,46 @@ const (
 	defaultHoursPerDay        = 8
 	defaultDaysPerMonth       = 24
 	defaultJiraRestApiVersion = "3"
+	defaultReport             = "timespent"
+	reportJson                = "json"
+	defaultInvoiceBy          = "project"
+	jiraTimeLayout            = "2006-01-02T15:04:05.000-0700"
 	usageText                 = `Usage of jira-timespent-report (v%s):
-  $ jira-timespent-report [options]
+  $ jira-timespent-report [options] [command [options]]
+
+Commands:
+  report     print csv report (default)
+  sync       save issues and worklogs to the database
+  snapshots  print snapshots of the database
+  diff       print changes between two snapshots or saved reports
+  lock       close the target month
+  unlock     reopen the target month
+  check      print worklog changes in closed months (exit status 1 if any, 4 on errors)
 
 Example:
   # get csv report by cli
   $ AUTH_USER=yyyy AUTH_TOKEN=aaaabbbb jira-timespent-report -url https://your-jira.atlassian.net -maxresult 10 -unit dd -query "status = Closed" -targetym 2020-08
 
+  # save to the database and get csv report from the database
+  $ AUTH_USER=yyyy AUTH_TOKEN=aaaabbbb jira-timespent-report -url https://your-jira.atlassian.net -targetym 2020-08 -db history.db sync
+  $ jira-timespent-report -targetym 2020-08 -db history.db report -from-db
+
+  # send csv report by mail
+  $ AUTH_USER=yyyy AUTH_TOKEN=aaaabbbb SMTP_USER=report SMTP_PASSWORD=xxxx jira-timespent-report -url https://your-jira.atlassian.net -worklog -report timesheet -targetym 2020-08 -smtp-host smtp.example.com:587 -smtp-from report@example.com -mail-to finance@example.com
+
   # get csv report by http server
-  $ AUTH_USER=yyyy AUTH_TOKEN=aaaabbbb jira-timespent-report -server &
-  $ curl localhost:8080/?url=https://your-jira.atlassian.net&maxresult=10&unit=dd&query=status+%%3DClosed&targetym=2020-08
+  $ AUTH_USER=yyyy AUTH_TOKEN=aaaabbbb jira-timespent-report -url https://your-jira.atlassian.net -server &
+  $ curl "localhost:8080/report?maxresult=10&timeunit=dd&query=status+%%3DClosed&targetyearmonth=2020-08"
+
+  # build and download reports in the browser
+  $ open http://localhost:8080/
+
+  # get json report by http api
+  $ curl -X POST localhost:8080/api/v1/report -H "Content-Type: application/json" -d '{"targetYearMonth":"2020-08","report":"timesheet"}'
+
+  # run scheduled reports by http server and browse the history
+  $ AUTH_USER=yyyy AUTH_TOKEN=aaaabbbb jira-timespent-report -url https://your-jira.atlassian.net -server -schedules schedules.yaml -smtp-host smtp.example.com:587 -smtp-from report@example.com &
+  $ curl localhost:8080/runs
 
 Options:
 `
@@ -58,12 +135,51 @@ var (
 		"started":                       "開始日時",
 		"author.displayname":            "表示名",
 		"author.emailaddress":           "メールアドレス",
+		"author.accountid":              "アカウントID",
+		"author.employeeid":             "社員番号",
+		"author.team":                   "チーム",
+		"author.costcentre":             "コストセンター",
 		"timespentseconds":              "消費時間",
+		"issuetype":                     "課題タイプ",
+		"created":                       "作成日時",
+		"resolutiondate":                "解決日時",
+		"project":                       "プロジェクト",
+		"query":                         "検索条件名",
+		"parent":                        "親課題",
+		"site":                          "サイト",
+		"updated":                       "更新日時",
 	}
 )
 
-func (c *Config) SetQueryParams(queryParams url.Values) {
+func (c *Config) SetQueryParams(queryParams url.Values) error {
+
+	invalid := make([]string, 0, 2)
+	atoi := func(key string, value string, dst *int) {
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s=[%v]", key, value))
+			return
+		}
+		*dst = i
+	}
+	parseBool := func(key string, value string, dst *bool) {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s=[%v]", key, value))
+			return
+		}
+		*dst = b
+	}
+	parseFloat := func(key string, value string, dst *float64) {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s=[%v]", key, value))
+			return
+		}
+		*dst = f
+	}
 
+	queries := make(NamedQueries, 0, 10)
 	for key, vs := range queryParams {
 		if len(vs) == 0 {
 			continue
@@ -71,72 +187,197 @@ func (c *Config) SetQueryParams(queryParams url.Values) {
 		value := vs[0]
 
 		switch strings.ToLower(key) {
+		case "namedquery", "namedfilter":
+			for _, v := range vs {
+				q, err := parseNamedQuery(v, strings.ToLower(key) == "namedfilter")
+				if err != nil {
+					invalid = append(invalid, fmt.Sprintf("%s=[%v]", key, v))
+					continue
+				}
+				queries = append(queries, q)
+			}
 		case "baseurl":
-			c.BaseURL = value
+			invalid = append(invalid, fmt.Sprintf("%s=[%v]", key, value))
 		case "query":
 			c.Query = value
 		case "filter":
 			c.Filter = value
+		case "project":
+			c.Projects = value
+		case "assignee":
+			c.Assignees = value
+		case "status":
+			c.Statuses = value
+		case "issuetype":
+			c.IssueTypes = value
+		case "label":
+			c.Labels = value
+		case "worklogauthor":
+			c.WorklogAuthors = value
 		case "fieldnames":
 			c.FieldNames = value
 		case "maxresult":
-			i, _ := strconv.Atoi(value)
-			c.MaxResult = i
+			atoi(key, value, &c.MaxResult)
 		case "apiversion":
 			c.ApiVersion = value
+		case "searchapi":
+			c.SearchApi = value
 		case "timeunit":
 			c.TimeUnit = value
 		case "hoursperday":
-			i, _ := strconv.Atoi(value)
-			c.HoursPerDay = i
+			atoi(key, value, &c.HoursPerDay)
 		case "dayspermonth":
-			i, _ := strconv.Atoi(value)
-			c.DaysPerMonth = i
+			atoi(key, value, &c.DaysPerMonth)
 		case "worklog":
-			b, _ := strconv.ParseBool(value)
-			c.Worklog = b
+			parseBool(key, value, &c.Worklog)
+		case "dryrun":
+			parseBool(key, value, &c.DryRun)
+		case "fromdb":
+			parseBool(key, value, &c.FromDb)
+		case "snapshot":
+			atoi(key, value, &c.Snapshot)
+		case "difffrom":
+			if _, err := strconv.Atoi(value); err != nil {
+				invalid = append(invalid, fmt.Sprintf("%s=[%v]", key, value))
+				continue
+			}
+			c.DiffFrom = value
+		case "diffto":
+			if _, err := strconv.Atoi(value); err != nil {
+				invalid = append(invalid, fmt.Sprintf("%s=[%v]", key, value))
+				continue
+			}
+			c.DiffTo = value
 		case "targetyearmonth":
 			c.TargetYearMonth = value
+		case "report":
+			c.Report = value
+		case "minhours":
+			parseFloat(key, value, &c.MinHours)
+		case "maxhours":
+			parseFloat(key, value, &c.MaxHours)
+		case "invoiceby":
+			c.InvoiceBy = value
+		case "teamgroups":
+			c.TeamGroups = value
+		case "teamallocation":
+			c.TeamAllocation = value
+		case "roundmode":
+			c.RoundMode = value
+		case "roundmethod":
+			c.RoundMethod = value
+		case "roundincrement":
+			parseFloat(key, value, &c.RoundIncrement)
+		case "precision":
+			atoi(key, value, &c.Precision)
 		}
 	}
+
+	if len(queries) > 0 {
+		sort.SliceStable(queries, func(i, j int) bool {
+			return queries[i].Name < queries[j].Name
+		})
+		c.Queries = queries
+	}
+
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return fmt.Errorf("invalid query parameter: %v", strings.Join(invalid, ", "))
+	}
+
+	return nil
 }
 
 func (c *Config) fields() []string {
 
-	if c.Worklog {
-		return []string{
+	if c.collectWorklog() {
+		fields := []string{
 			"started",
 			"author.displayname",
 			"author.emailaddress",
+			"author.accountid",
 			"timespentseconds",
 		}
+		if c.hasNamedQueries() {
+			fields = append(fields, "query")
+		}
+		if c.multiSite() {
+			fields = append(fields, "site")
+		}
+		return append(fields, c.userFields()...)
 	}
 
-	return strings.Split(c.FieldNames, ",")
+	fields := strings.Split(c.FieldNames, ",")
+	if c.hasNamedQueries() {
+		fields = append(fields, "query")
+	}
+	if c.multiSite() {
+		fields = append(fields, "site")
+	}
+	return fields
 }
 
-func (c *Config) checkAuthEnv() error {
+func (c *Config) searchFields() []string {
 
-	user := os.Getenv("AUTH_USER")
-	token := os.Getenv("AUTH_TOKEN")
+	if c.Command == commandSync || c.Report == reportJson {
+		return storeFields
+	}
 
-	if len(user) == 0 || len(token) == 0 {
-		return fmt.Errorf("環境変数 AUTH_USER/AUTH_TOKEN が未定義")
+	switch c.Report {
+	case "status":
+		return []string{
+			"summary",
+			"status",
+			"issuetype",
+			"created",
+			"resolutiondate",
+		}
+	case "cost":
+		return []string{
+			"summary",
+			"issuetype",
+			"project",
+			"parent",
+		}
 	}
 
-	return nil
+	return c.fields()
+}
+
+func (c *Config) collectWorklog() bool {
+
+	switch c.Report {
+	case "timesheet", "compliance", "cost", "team":
+		return true
+	}
+
+	switch c.Command {
+	case commandSync, commandLock, commandCheck:
+		return true
+	}
+
+	return c.Worklog
+}
+
+func (c *Config) expandChangelog() bool {
+
+	return c.Report == "status" || c.Command == commandSync
 }
 
-func (c *Config) basicAuthorization() string {
+func (c *Config) checkAuthEnv() error {
 
-	if err := c.checkAuthEnv(); err != nil {
-		panic(err)
+	sites, err := c.sites()
+	if err != nil {
+		return err
 	}
 
-	user := os.Getenv("AUTH_USER")
-	token := os.Getenv("AUTH_TOKEN")
+	for _, site := range sites {
+		if err := site.checkAuth(); err != nil {
+			return err
+		}
+	}
 
-	return fmt.Sprintf("Basic %s", base64.URLEncoding.EncodeToString([]byte(user+":"+token)))
+	return nil
 }
 
 func (c *Config) dateCondition() (string, bool) {
@@ -164,59 +405,26 @@ func (c *Config) dateCondition() (string, bool) {
 		offset = -monthDiff - yearDiff
 	}
 
-	if c.Worklog {
+	if c.collectWorklog() {
 		return fmt.Sprintf("worklogDate >= startOfMonth(%d) AND worklogDate <= endOfMonth(%d)", offset, offset), true
 	}
 
 	return fmt.Sprintf("updated >= startOfMonth(%d) AND updated <= endOfMonth(%d)", offset, offset), true
 }
 
-func (c *Config) FilterURL(filterID string) (*url.URL, error) {
-
-	u, err := url.Parse(c.BaseURL)
-	if err != nil {
-		return nil, fmt.Errorf("url.Parse error: %v\nBaseURL=[%v]", err, c.BaseURL)
-	}
-
-	u.Path = fmt.Sprintf("/rest/api/%s/filter/%s", c.ApiVersion, filterID)
-
-	return u, nil
-}
-
-func (c *Config) SearchURL() (*url.URL, error) {
-
-	u, err := url.Parse(c.BaseURL)
-	if err != nil {
-		return nil, fmt.Errorf("url.Parse error: %v\nBaseURL=[%v]", err, c.BaseURL)
-	}
-
-	u.Path = fmt.Sprintf("/rest/api/%s/search", c.ApiVersion)
-
-	return u, nil
-}
-
-func (c *Config) WorklogURL(key string, queryParams url.Values) (*url.URL, error) {
-
-	u, err := url.Parse(c.BaseURL)
-	if err != nil {
-		return nil, fmt.Errorf("url.Parse error: %v\nBaseURL=[%v]", err, c.BaseURL)
-	}
-
-	u.Path = fmt.Sprintf("/rest/api/%s/issue/%s/worklog", c.ApiVersion, key)
-	u.RawQuery = queryParams.Encode()
-
-	return u, nil
-}
-
-func (c *Config) WithTimeUnit(second int) float32 {
+func (c *Config) WithTimeUnit(second int) float64 {
 
 	switch strings.ToLower(c.TimeUnit) {
 	case "h", "hh":
-		return float32(second) / float32(60*60)
+		return float64(second) / float64(60*60)
 	case "d", "dd":
-		return float32(second) / float32(60*60*c.HoursPerDay)
+		return float64(second) / float64(60*60*c.HoursPerDay)
 	case "m", "mm":
-		return float32(second) / float32(60*60*c.HoursPerDay*c.DaysPerMonth)
+		days, err := c.workingDaysPerMonth()
+		if err != nil {
+			return 0.0
+		}
+		return float64(second) / float64(60*60*c.HoursPerDay*days)
 	default:
 		return 0.0
 	}
@@ -233,10 +441,20 @@ func (c *Config) TargetMonth() (*time.Time, error) {
 		return &t, nil
 	}
 
-	t := time.Now().AddDate(0, -1, 0)
+	t := c.clock().AddDate(0, -1, 0)
 	return &t, nil
 }
 
+func (c *Config) inTargetMonth(t time.Time) bool {
+
+	target, err := c.TargetMonth()
+	if err != nil {
+		return false
+	}
+
+	return t.Year() == target.Year() && t.Month() == target.Month()
+}
+
 func (c *Config) StartedAfter() string {
 	t, err := c.TargetMonth()
 	if err != nil {
@@ -0,0 +1,332 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Rate struct {
+	Author    string  `json:"author,omitempty"`
+	Role      string  `json:"role,omitempty"`
+	Issuetype string  `json:"issuetype,omitempty"`
+	Rate      float64 `json:"rate"`
+	Currency  string  `json:"currency"`
+	From      string  `json:"from,omitempty"`
+	To        string  `json:"to,omitempty"`
+}
+
+type CostRounding struct {
+	Increment string `json:"increment,omitempty"`
+	Method    string `json:"method,omitempty"`
+}
+
+type RateCard struct {
+	Roles    map[string]string `json:"roles"`
+	Rates    []Rate            `json:"rates"`
+	Rounding CostRounding      `json:"rounding"`
+}
+
+type CostEntry struct {
+	Worklog   WorklogField
+	Issue     Issue
+	Billable  int
+	Rate      Rate
+	Amount    float64
+	Unmatched bool
+}
+
+type InvoiceLine struct {
+	Group    string
+	Currency string
+	Billable int
+	Amount   float64
+}
+
+func LoadRateCard(r io.Reader) (*RateCard, error) {
+
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ioutil.ReadAll error: %v", err)
+	}
+
+	var rateCard RateCard
+	if err := json.Unmarshal(body, &rateCard); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error: %v\nbody=[%v]", err, string(body))
+	}
+
+	for _, rate := range rateCard.Rates {
+		for _, value := range []string{rate.From, rate.To} {
+			if len(value) == 0 {
+				continue
+			}
+			if _, err := time.Parse(dateLayout, value); err != nil {
+				return nil, fmt.Errorf("time.Parse error: %v\nrate=[%v]", err, rate)
+			}
+		}
+	}
+
+	if _, err := rateCard.Rounding.increment(); err != nil {
+		return nil, err
+	}
+
+	return &rateCard, nil
+}
+
+func (c *Config) rateCard() (*RateCard, error) {
+
+	if len(c.RateCard) == 0 {
+		return nil, fmt.Errorf("rate card is not specified")
+	}
+
+	f, err := os.Open(c.RateCard)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open error: %v\nRateCard=[%v]", err, c.RateCard)
+	}
+	defer f.Close()
+
+	return LoadRateCard(f)
+}
+
+func (r *CostRounding) increment() (int, error) {
+
+	if len(r.Increment) == 0 {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(r.Increment)
+	if err != nil {
+		return 0, fmt.Errorf("time.ParseDuration error: %v\nincrement=[%v]", err, r.Increment)
+	}
+
+	return int(d.Seconds()), nil
+}
+
+func (r *CostRounding) round(second int) int {
+
+	increment, _ := r.increment()
+	if increment <= 0 {
+		return second
+	}
+
+	units := float64(second) / float64(increment)
+	switch strings.ToLower(r.Method) {
+	case "down":
+		units = math.Floor(units)
+	case "nearest":
+		units = math.Floor(units + 0.5)
+	default:
+		units = math.Ceil(units)
+	}
+
+	return int(units) * increment
+}
+
+func (r *Rate) effective(date time.Time) bool {
+
+	day := date.Format(dateLayout)
+	if len(r.From) > 0 && day < r.From {
+		return false
+	}
+	if len(r.To) > 0 && day > r.To {
+		return false
+	}
+
+	return true
+}
+
+func (rc *RateCard) rateOf(worklog WorklogField, issue Issue) (Rate, bool) {
+
+	started, err := worklog.StartedTime()
+	if err != nil {
+		return Rate{}, false
+	}
+
+	role, ok := rc.Roles[worklog.Author.AccountId]
+	if !ok {
+		role = rc.Roles[worklog.Author.Emailaddress]
+	}
+
+	matchers := []func(r Rate) bool{
+		func(r Rate) bool {
+			return worklog.Author.matches(r.Author)
+		},
+		func(r Rate) bool {
+			return len(r.Role) > 0 && r.Role == role
+		},
+		func(r Rate) bool {
+			return len(r.Issuetype) > 0 && r.Issuetype == issue.Fields.Issuetype.Name
+		},
+		func(r Rate) bool {
+			return len(r.Author) == 0 && len(r.Role) == 0 && len(r.Issuetype) == 0
+		},
+	}
+	for _, match := range matchers {
+		for _, rate := range rc.Rates {
+			if match(rate) && rate.effective(started) {
+				return rate, true
+			}
+		}
+	}
+
+	return Rate{}, false
+}
+
+func (rc *RateCard) Costs(worklogs Worklogs, issues map[string]Issue) []CostEntry {
+
+	entries := make([]CostEntry, 0, len(worklogs))
+	for _, worklog := range worklogs {
+		issue := issues[worklog.siteKey()]
+		entry := CostEntry{
+			Worklog:  worklog,
+			Issue:    issue,
+			Billable: rc.Rounding.round(worklog.Timespentseconds),
+		}
+
+		rate, ok := rc.rateOf(worklog, issue)
+		if ok {
+			entry.Rate = rate
+			entry.Amount = rate.Rate * float64(entry.Billable) / float64(60*60)
+		} else {
+			entry.Unmatched = true
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func (i *Issue) epicKey() string {
+
+	if i.Fields.Issuetype.HierarchyLevel == 1 {
+		return i.Key
+	}
+
+	if i.Fields.Parent != nil && i.Fields.Parent.Fields.Issuetype.HierarchyLevel == 1 {
+		return i.Fields.Parent.Key
+	}
+
+	return ""
+}
+
+func (i *Issue) projectKey() string {
+
+	if len(i.Fields.Project.Key) > 0 {
+		return i.Fields.Project.Key
+	}
+
+	if n := strings.LastIndex(i.Key, "-"); n > 0 {
+		return i.Key[:n]
+	}
+
+	return i.Key
+}
+
+func Invoice(entries []CostEntry, groupBy string) []InvoiceLine {
+
+	lines := map[string]*InvoiceLine{}
+	for _, entry := range entries {
+		if entry.Unmatched {
+			continue
+		}
+
+		issue := entry.Issue
+		if len(issue.Key) == 0 {
+			issue.Key = entry.Worklog.Key
+		}
+
+		group := issue.projectKey()
+		if groupBy == "epic" {
+			group = issue.epicKey()
+		}
+
+		key := group + "\t" + entry.Rate.Currency
+		line, ok := lines[key]
+		if !ok {
+			line = &InvoiceLine{Group: group, Currency: entry.Rate.Currency}
+			lines[key] = line
+		}
+		line.Billable += entry.Billable
+		line.Amount += entry.Amount
+	}
+
+	result := make([]InvoiceLine, 0, len(lines))
+	for _, line := range lines {
+		result = append(result, *line)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Group == result[j].Group {
+			return result[i].Currency < result[j].Currency
+		}
+		return result[i].Group < result[j].Group
+	})
+
+	return result
+}
+
+func (results IssueSearchResults) issueMap() map[string]Issue {
+
+	issues := map[string]Issue{}
+	for _, result := range results {
+		for _, issue := range result.Issues {
+			issues[issue.siteKey()] = issue
+		}
+	}
+
+	return issues
+}
+
+func CostTables(issues IssueSearchResults, worklogs WorklogResults) (Tables, error) {
+
+	rateCard, err := config.rateCard()
+	if err != nil {
+		return nil, fmt.Errorf("config.rateCard error: %v", err)
+	}
+
+	entries := rateCard.Costs(worklogs.inTargetMonth(), issues.issueMap())
+
+	costs := newTable("キー", "開始日時", "表示名", "メールアドレス", "課題タイプ", "消費時間(h)", "請求時間(h)", "単価", "通貨", "金額")
+	for _, entry := range entries {
+		record := []string{
+			entry.Worklog.Key,
+			entry.Worklog.Started,
+			entry.Worklog.Author.Displayname,
+			entry.Worklog.Author.Emailaddress,
+			entry.Issue.Fields.Issuetype.Name,
+			config.formatNumber(float64(entry.Worklog.Timespentseconds) / float64(60*60)),
+			config.formatNumber(float64(entry.Billable) / float64(60*60)),
+			"",
+			"",
+			"",
+		}
+		if !entry.Unmatched {
+			record[7] = fmt.Sprintf("%.2f", entry.Rate.Rate)
+			record[8] = entry.Rate.Currency
+			record[9] = fmt.Sprintf("%.2f", entry.Amount)
+		}
+		costs.append(record)
+	}
+
+	groupLabel := "プロジェクト"
+	if config.InvoiceBy == "epic" {
+		groupLabel = "エピック"
+	}
+	invoice := newTable(groupLabel, "通貨", "請求時間(h)", "金額")
+	for _, line := range Invoice(entries, config.InvoiceBy) {
+		invoice.append([]string{
+			line.Group,
+			line.Currency,
+			config.formatNumber(float64(line.Billable) / float64(60*60)),
+			fmt.Sprintf("%.2f", line.Amount),
+		})
+	}
+
+	return Tables{costs, invoice}, nil
+}
@@ -0,0 +1,58 @@
+package jira
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRateCard_Costs(t *testing.T) {
+	rateCard, err := LoadRateCard(strings.NewReader(`{
+  "roles": {"bob@example.com": "engineer"},
+  "rates": [
+    {"author": "alice@example.com", "rate": 10000, "currency": "JPY", "to": "2020-08-15"},
+    {"role": "engineer", "rate": 8000, "currency": "JPY"},
+    {"issuetype": "Bug", "rate": 100, "currency": "USD"},
+    {"rate": 5000, "currency": "JPY"}
+  ],
+  "rounding": {"increment": "15m", "method": "up"}
+}`))
+	if err != nil {
+		t.Fatalf("LoadRateCard() error = %v", err)
+	}
+
+	newWorklog := func(key string, email string, started string, second int) WorklogField {
+		worklog := WorklogField{Key: key, Started: started, Timespentseconds: second}
+		worklog.Author.Emailaddress = email
+		return worklog
+	}
+	worklogs := Worklogs{
+		newWorklog("TEST-1", "alice@example.com", "2020-08-03T09:00:00.000+0900", 50*60),
+		newWorklog("TEST-1", "alice@example.com", "2020-08-17T09:00:00.000+0900", 60*60),
+		newWorklog("TEST-2", "bob@example.com", "2020-08-03T09:00:00.000+0900", 60*60),
+		newWorklog("OTHER-1", "carol@example.com", "2020-08-03T09:00:00.000+0900", 30*60),
+	}
+	issues := map[string]Issue{
+		"TEST-1":  {Key: "TEST-1", Fields: IssueField{Issuetype: IssueType{Name: "Task"}, Project: Project{Key: "TEST"}}},
+		"TEST-2":  {Key: "TEST-2", Fields: IssueField{Issuetype: IssueType{Name: "Bug"}, Project: Project{Key: "TEST"}}},
+		"OTHER-1": {Key: "OTHER-1", Fields: IssueField{Issuetype: IssueType{Name: "Bug"}}},
+	}
+
+	entries := rateCard.Costs(worklogs, issues)
+	expected := []float64{10000, 5000, 8000, 50}
+	for i, amount := range expected {
+		if entries[i].Amount != amount {
+			t.Errorf("expected=[%v] <> actual=[%v]\n", amount, entries[i].Amount)
+		}
+	}
+
+	lines := Invoice(entries, "project")
+	if len(lines) != 2 {
+		t.Fatalf("expected=[2] <> actual=[%v]\n", lines)
+	}
+	if lines[0].Group != "OTHER" || lines[0].Currency != "USD" || lines[0].Amount != 50 {
+		t.Errorf("unexpected invoice line: %v\n", lines[0])
+	}
+	if lines[1].Group != "TEST" || lines[1].Amount != 23000 || lines[1].Billable != 3*60*60 {
+		t.Errorf("unexpected invoice line: %v\n", lines[1])
+	}
+}
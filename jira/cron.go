@@ -0,0 +1,154 @@
+package jira
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxCronSearchMinutes = 60 * 24 * 366 * 5
+
+type cronField struct {
+	min int
+	max int
+}
+
+var cronFields = []cronField{
+	{min: 0, max: 59},
+	{min: 0, max: 23},
+	{min: 1, max: 31},
+	{min: 1, max: 12},
+	{min: 0, max: 7},
+}
+
+type Cron struct {
+	expression string
+	minutes    map[int]bool
+	hours      map[int]bool
+	days       map[int]bool
+	months     map[int]bool
+	weekdays   map[int]bool
+	anyDay     bool
+	anyWeekday bool
+}
+
+func ParseCron(expression string) (*Cron, error) {
+
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression must have %d fields: %v", len(cronFields), expression)
+	}
+
+	values := make([]map[int]bool, len(fields))
+	for i, field := range fields {
+		v, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("parseCronField error: %v\nexpression=[%v]", err, expression)
+		}
+		values[i] = v
+	}
+
+	if values[4][7] {
+		values[4][0] = true
+	}
+
+	return &Cron{
+		expression: expression,
+		minutes:    values[0],
+		hours:      values[1],
+		days:       values[2],
+		months:     values[3],
+		weekdays:   values[4],
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, bounds cronField) (map[int]bool, error) {
+
+	values := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return nil, fmt.Errorf("invalid step: %v", part)
+			}
+			step = s
+			part = part[:i]
+		}
+
+		low, high := bounds.min, bounds.max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			i := strings.Index(part, "-")
+			l, err := strconv.Atoi(part[:i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid range: %v", part)
+			}
+			h, err := strconv.Atoi(part[i+1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid range: %v", part)
+			}
+			low, high = l, h
+		default:
+			v, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value: %v", part)
+			}
+			low, high = v, v
+			if step > 1 {
+				high = bounds.max
+			}
+		}
+
+		if low < bounds.min || high > bounds.max || low > high {
+			return nil, fmt.Errorf("out of range: %v", part)
+		}
+		for v := low; v <= high; v += step {
+			values[v] = true
+		}
+	}
+
+	return values, nil
+}
+
+func (c *Cron) String() string {
+
+	return c.expression
+}
+
+func (c *Cron) Match(t time.Time) bool {
+
+	if !c.minutes[t.Minute()] || !c.hours[t.Hour()] || !c.months[int(t.Month())] {
+		return false
+	}
+
+	day := c.days[t.Day()]
+	weekday := c.weekdays[int(t.Weekday())]
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	}
+
+	return day || weekday
+}
+
+func (c *Cron) Next(after time.Time) (time.Time, bool) {
+
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	for i := 0; i < maxCronSearchMinutes; i++ {
+		if c.Match(t) {
+			return t, true
+		}
+		t = t.Add(time.Minute)
+	}
+
+	return time.Time{}, false
+}
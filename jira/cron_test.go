@@ -0,0 +1,58 @@
+package jira
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCron_Next(t *testing.T) {
+	after := time.Date(2020, 8, 31, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expression string
+		expected   time.Time
+	}{
+		{expression: "* * * * *", expected: time.Date(2020, 8, 31, 10, 31, 0, 0, time.UTC)},
+		{expression: "0 9 * * *", expected: time.Date(2020, 9, 1, 9, 0, 0, 0, time.UTC)},
+		{expression: "*/15 10 * * *", expected: time.Date(2020, 8, 31, 10, 45, 0, 0, time.UTC)},
+		{expression: "0 9 1 * *", expected: time.Date(2020, 9, 1, 9, 0, 0, 0, time.UTC)},
+		{expression: "0 9 * * 5", expected: time.Date(2020, 9, 4, 9, 0, 0, 0, time.UTC)},
+		{expression: "0 9 * * 7", expected: time.Date(2020, 9, 6, 9, 0, 0, 0, time.UTC)},
+		{expression: "0 9 15 * 1-2", expected: time.Date(2020, 9, 1, 9, 0, 0, 0, time.UTC)},
+		{expression: "0 0 29 2 *", expected: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			cron, err := ParseCron(tt.expression)
+			if err != nil {
+				t.Fatalf("ParseCron() error = %v", err)
+			}
+			actual, ok := cron.Next(after)
+			if !ok || !actual.Equal(tt.expected) {
+				t.Errorf("expected=[%v] <> actual[%v]\n", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseCron_Error(t *testing.T) {
+	tests := []string{
+		"",
+		"0 9 * *",
+		"60 * * * *",
+		"0 24 * * *",
+		"0 9 0 * *",
+		"0 9 * 13 *",
+		"0 9 * * 8",
+		"0 9 * * 5-1",
+		"*/0 * * * *",
+		"a * * * *",
+	}
+	for _, expression := range tests {
+		t.Run(expression, func(t *testing.T) {
+			if _, err := ParseCron(expression); err == nil {
+				t.Errorf("expected=[error] <> actual[%v]\n", err)
+			}
+		})
+	}
+}
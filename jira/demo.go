@@ -0,0 +1,30 @@
+package jira
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"bitbucket.org/yujiorama/jira-timespent-report/jira/jiratest"
+)
+
+const demoTargetYearMonth = "2020-08"
+
+func startDemo() error {
+
+	server, err := jiratest.NewServer()
+	if err != nil {
+		return fmt.Errorf("jiratest.NewServer error: %v", err)
+	}
+
+	config.BaseURL = server.URL
+	config.Sites = ""
+	if len(config.TargetYearMonth) == 0 {
+		config.TargetYearMonth = demoTargetYearMonth
+	}
+	os.Setenv("AUTH_USER", server.User)
+	os.Setenv("AUTH_TOKEN", server.Token)
+
+	log.Printf("demo: fake jira server started: url=[%v],targetym=[%v]\n", server.URL, config.TargetYearMonth)
+	return nil
+}
@@ -0,0 +1,402 @@
+package jira
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+const (
+	changeAdded   = "追加"
+	changeRemoved = "削除"
+	changeChanged = "変更"
+)
+
+type IssueChange struct {
+	Change string
+	Before *Issue
+	After  *Issue
+}
+
+type WorklogChange struct {
+	Change string
+	Before *WorklogField
+	After  *WorklogField
+}
+
+type TimeDelta struct {
+	Key    string
+	Author User
+	Issue  *Issue
+	Before TimeTotal
+	After  TimeTotal
+}
+
+type DiffResult struct {
+	Issues   []IssueChange
+	Worklogs []WorklogChange
+	Authors  []TimeDelta
+	Totals   []TimeDelta
+}
+
+func (d *DiffResult) IsEmpty() bool {
+
+	return len(d.Issues) == 0 && len(d.Worklogs) == 0
+}
+
+func (c *IssueChange) issue() *Issue {
+
+	if c.After != nil {
+		return c.After
+	}
+
+	return c.Before
+}
+
+func (c *WorklogChange) worklog() *WorklogField {
+
+	if c.After != nil {
+		return c.After
+	}
+
+	return c.Before
+}
+
+func (w *WorklogField) diffKey(byId bool) string {
+
+	if byId {
+		return w.Site + "\t" + w.Id
+	}
+
+	return w.siteKey() + "\t" + w.Started + "\t" + w.authorKey()
+}
+
+func hasWorklogIds(worklogs Worklogs) bool {
+
+	for _, worklog := range worklogs {
+		if len(worklog.Id) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (e *Export) worklogsInTargetMonth() Worklogs {
+
+	return WorklogResults{{Worklogs: e.Worklogs}}.inTargetMonth()
+}
+
+func DiffExports(before *Export, after *Export) *DiffResult {
+
+	result := &DiffResult{}
+
+	if before.rounded || after.rounded {
+		before, after = before.roundTimes(), after.roundTimes()
+	}
+
+	issues := map[string]*Issue{}
+	for i := range before.Issues {
+		issues[before.Issues[i].siteKey()] = &before.Issues[i]
+	}
+	seen := map[string]bool{}
+	for i := range after.Issues {
+		issue := &after.Issues[i]
+		key := issue.siteKey()
+		seen[key] = true
+
+		old, ok := issues[key]
+		switch {
+		case !ok:
+			result.Issues = append(result.Issues, IssueChange{Change: changeAdded, After: issue})
+		case issueChanged(before, old, after, issue):
+			result.Issues = append(result.Issues, IssueChange{Change: changeChanged, Before: old, After: issue})
+		}
+	}
+	for i := range before.Issues {
+		if !seen[before.Issues[i].siteKey()] {
+			result.Issues = append(result.Issues, IssueChange{Change: changeRemoved, Before: &before.Issues[i]})
+			seen[before.Issues[i].siteKey()] = true
+		}
+	}
+	sort.SliceStable(result.Issues, func(i, j int) bool {
+		return result.Issues[i].issue().siteKey() < result.Issues[j].issue().siteKey()
+	})
+
+	if before.noWorklogs || after.noWorklogs {
+		return result
+	}
+
+	beforeWorklogs := before.worklogsInTargetMonth()
+	afterWorklogs := after.worklogsInTargetMonth()
+	byId := hasWorklogIds(beforeWorklogs) && hasWorklogIds(afterWorklogs)
+
+	worklogs := map[string]*WorklogField{}
+	for i := range beforeWorklogs {
+		worklogs[beforeWorklogs[i].diffKey(byId)] = &beforeWorklogs[i]
+	}
+	seen = map[string]bool{}
+	for i := range afterWorklogs {
+		worklog := &afterWorklogs[i]
+		key := worklog.diffKey(byId)
+		seen[key] = true
+
+		old, ok := worklogs[key]
+		switch {
+		case !ok:
+			result.Worklogs = append(result.Worklogs, WorklogChange{Change: changeAdded, After: worklog})
+		case worklogChanged(old, worklog):
+			result.Worklogs = append(result.Worklogs, WorklogChange{Change: changeChanged, Before: old, After: worklog})
+		}
+	}
+	for i := range beforeWorklogs {
+		if !seen[beforeWorklogs[i].diffKey(byId)] {
+			result.Worklogs = append(result.Worklogs, WorklogChange{Change: changeRemoved, Before: &beforeWorklogs[i]})
+		}
+	}
+	sort.SliceStable(result.Worklogs, func(i, j int) bool {
+		a, b := result.Worklogs[i].worklog(), result.Worklogs[j].worklog()
+		if a.siteKey() != b.siteKey() {
+			return a.siteKey() < b.siteKey()
+		}
+		return a.Started < b.Started
+	})
+
+	result.Authors = timeDeltas(beforeWorklogs, afterWorklogs, func(w *WorklogField) string { return w.authorKey() })
+	result.Totals = timeDeltas(beforeWorklogs, afterWorklogs, func(w *WorklogField) string { return w.siteKey() })
+	for i := range result.Totals {
+		for _, export := range []*Export{after, before} {
+			if result.Totals[i].Issue != nil {
+				break
+			}
+			for j := range export.Issues {
+				if export.Issues[j].siteKey() == result.Totals[i].Key {
+					result.Totals[i].Issue = &export.Issues[j]
+					break
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+func issueChanged(beforeExport *Export, before *Issue, afterExport *Export, after *Issue) bool {
+
+	compare := func(field string) bool {
+		return beforeExport.hasIssueField(field) && afterExport.hasIssueField(field)
+	}
+
+	if compare("summary") && before.Fields.Summary != after.Fields.Summary {
+		return true
+	}
+	if compare("status") && before.Fields.Status.Name != after.Fields.Status.Name {
+		return true
+	}
+
+	return compare("timespent") && before.Fields.Timespent != after.Fields.Timespent
+}
+
+func worklogChanged(before *WorklogField, after *WorklogField) bool {
+
+	return before.Timespentseconds != after.Timespentseconds ||
+		before.Started != after.Started ||
+		before.authorKey() != after.authorKey()
+}
+
+func timeDeltas(before Worklogs, after Worklogs, keyOf func(*WorklogField) string) []TimeDelta {
+
+	deltas := map[string]*TimeDelta{}
+	delta := func(worklog *WorklogField) *TimeDelta {
+		key := keyOf(worklog)
+		d, ok := deltas[key]
+		if !ok {
+			d = &TimeDelta{Key: key}
+			deltas[key] = d
+		}
+		d.Author = worklog.Author
+		return d
+	}
+
+	for i := range before {
+		delta(&before[i]).Before.Add(before[i].Timespentseconds)
+	}
+	for i := range after {
+		delta(&after[i]).After.Add(after[i].Timespentseconds)
+	}
+
+	result := make([]TimeDelta, 0, len(deltas))
+	for _, d := range deltas {
+		if d.Before.Seconds() != d.After.Seconds() {
+			result = append(result, *d)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+
+	return result
+}
+
+func timeDeltaRecord(before TimeTotal, after TimeTotal) []string {
+
+	difference := after.Minus(before)
+	return []string{before.String(), after.String(), difference.String()}
+}
+
+func (d *DiffResult) RenderCsv(w io.Writer) error {
+
+	records := make([][]string, 0, 10)
+	records = append(records, []string{"対象", "変更", "キー", "作業ログID", "開始日時", "更新日時", "表示名", "メールアドレス", "変更前", "変更後", "差分"})
+	for _, change := range d.Issues {
+		var before, after TimeTotal
+		if change.Before != nil {
+			before.Add(change.Before.Fields.Timespent)
+		}
+		if change.After != nil {
+			after.Add(change.After.Fields.Timespent)
+		}
+		issue := change.issue()
+		record := []string{"課題", change.Change, issue.siteKey(), "", "", "", "", ""}
+		records = append(records, append(record, timeDeltaRecord(before, after)...))
+	}
+	for _, change := range d.Worklogs {
+		var before, after TimeTotal
+		if change.Before != nil {
+			before.Add(change.Before.Timespentseconds)
+		}
+		if change.After != nil {
+			after.Add(change.After.Timespentseconds)
+		}
+		worklog := change.worklog()
+		record := []string{"作業ログ", change.Change, worklog.siteKey(), worklog.Id, worklog.Started, worklog.Updated,
+			worklog.Author.Displayname, worklog.Author.Emailaddress}
+		records = append(records, append(record, timeDeltaRecord(before, after)...))
+	}
+
+	records = append(records, []string{"表示名", "メールアドレス", "アカウントID", "変更前", "変更後", "差分"})
+	for _, delta := range d.Authors {
+		record := []string{delta.Author.Displayname, delta.Author.Emailaddress, delta.Author.AccountId}
+		records = append(records, append(record, timeDeltaRecord(delta.Before, delta.After)...))
+	}
+
+	records = append(records, []string{"キー", "概要", "変更前", "変更後", "差分"})
+	for _, delta := range d.Totals {
+		summary := ""
+		if delta.Issue != nil {
+			summary = delta.Issue.Fields.Summary
+		}
+		record := []string{delta.Key, summary}
+		records = append(records, append(record, timeDeltaRecord(delta.Before, delta.After)...))
+	}
+
+	writer := csv.NewWriter(w)
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("writer.Write error: %v\nrecord=[%v]\n", err, record)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("writer.Error error: %v\n", err)
+	}
+
+	return nil
+}
+
+func (s *Store) export(id int) (*Export, error) {
+
+	issues, worklogs, err := s.Load(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewExport(issues, worklogs), nil
+}
+
+func isSnapshotSource(source string) bool {
+
+	if len(source) == 0 {
+		return true
+	}
+
+	_, err := strconv.Atoi(source)
+	return err == nil
+}
+
+func diffSource(store *Store, source string) (*Export, error) {
+
+	if !isSnapshotSource(source) {
+		return LoadExportFile(source)
+	}
+
+	id, _ := strconv.Atoi(source)
+	return store.export(id)
+}
+
+func previousSource(store *Store, source string) (*Export, error) {
+
+	if !isSnapshotSource(source) {
+		return store.export(0)
+	}
+
+	id, _ := strconv.Atoi(source)
+	current, err := store.snapshotId(id)
+	if err != nil {
+		return nil, err
+	}
+	if current <= 1 {
+		return nil, fmt.Errorf("no previous snapshot: %v", current)
+	}
+
+	return store.export(current - 1)
+}
+
+func diff(w io.Writer, before func(store *Store) (*Export, error), beforeFromStore bool) []error {
+
+	var store *Store
+	if beforeFromStore || isSnapshotSource(config.DiffTo) {
+		s, err := OpenStore(config.Database)
+		if err != nil {
+			return []error{fmt.Errorf("OpenStore error: %v", err)}
+		}
+		defer s.Close()
+		store = s
+	}
+
+	beforeExport, err := before(store)
+	if err != nil {
+		return []error{fmt.Errorf("diff source error: %v\nfrom=[%v]", err, config.DiffFrom)}
+	}
+
+	afterExport, err := diffSource(store, config.DiffTo)
+	if err != nil {
+		return []error{fmt.Errorf("diff source error: %v\nto=[%v]", err, config.DiffTo)}
+	}
+
+	if err := DiffExports(beforeExport, afterExport).RenderCsv(w); err != nil {
+		return []error{err}
+	}
+
+	return nil
+}
+
+func Diff(w io.Writer) []error {
+
+	return diff(w, func(store *Store) (*Export, error) {
+		if len(config.DiffFrom) == 0 {
+			return previousSource(store, config.DiffTo)
+		}
+		return diffSource(store, config.DiffFrom)
+	}, isSnapshotSource(config.DiffFrom))
+}
+
+func DiffExport(w io.Writer, r io.Reader, isJson bool) []error {
+
+	return diff(w, func(store *Store) (*Export, error) {
+		return LoadExport(r, isJson)
+	}, false)
+}
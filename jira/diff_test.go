@@ -0,0 +1,117 @@
+package jira
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiffExports(t *testing.T) {
+	saved := *config
+	defer func() { *config = saved }()
+	config.TargetYearMonth = "2020-08"
+	config.TimeUnit = "hh"
+	config.Sites = ""
+
+	alice := User{AccountId: "5b10a2844c20165700ede21g", Displayname: "Alice", Emailaddress: "alice@example.com"}
+	bob := User{AccountId: "5b10ac8d82e05b22cc7d4ef5", Displayname: "Bob", Emailaddress: "bob@example.com"}
+	issue := func(key string, summary string, second int) Issue {
+		return Issue{Key: key, Fields: IssueField{Summary: summary, Timespent: second}}
+	}
+	before := &Export{
+		Issues: Issues{issue("DEMO-1", "ログイン画面の作成", 43200), issue("DEMO-2", "ログイン画面の単体テスト", 14400)},
+		Worklogs: Worklogs{
+			{Key: "DEMO-1", Id: "30001", Author: alice, Started: "2020-08-03T09:00:00.000+0900", Timespentseconds: 28800},
+			{Key: "DEMO-1", Id: "30002", Author: bob, Started: "2020-08-04T13:00:00.000+0900", Timespentseconds: 14400},
+			{Key: "DEMO-2", Id: "30003", Author: bob, Started: "2020-08-05T09:00:00.000+0900", Timespentseconds: 14400},
+		},
+	}
+	after := &Export{
+		Issues: Issues{issue("DEMO-1", "ログイン画面の作成", 50400), issue("DEMO-3", "パスワード再設定でエラーになる", 3600)},
+		Worklogs: Worklogs{
+			{Key: "DEMO-1", Id: "30001", Author: alice, Started: "2020-08-03T09:00:00.000+0900", Timespentseconds: 36000},
+			{Key: "DEMO-1", Id: "30002", Author: bob, Started: "2020-08-04T13:00:00.000+0900", Timespentseconds: 14400},
+			{Key: "DEMO-3", Id: "30004", Author: alice, Started: "2020-08-31T17:00:00.000+0900", Timespentseconds: 3600, Updated: "2020-09-02T10:00:00.000+0900"},
+			{Key: "DEMO-3", Id: "30005", Author: alice, Started: "2020-09-01T09:00:00.000+0900", Timespentseconds: 3600},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := DiffExports(before, after).RenderCsv(&buf); err != nil {
+		t.Fatalf("RenderCsv() error = %v", err)
+	}
+
+	expected := "対象,変更,キー,作業ログID,開始日時,更新日時,表示名,メールアドレス,変更前,変更後,差分\n" +
+		"課題,変更,DEMO-1,,,,,,12.00,14.00,2.00\n" +
+		"課題,削除,DEMO-2,,,,,,4.00,0.00,-4.00\n" +
+		"課題,追加,DEMO-3,,,,,,0.00,1.00,1.00\n" +
+		"作業ログ,変更,DEMO-1,30001,2020-08-03T09:00:00.000+0900,,Alice,alice@example.com,8.00,10.00,2.00\n" +
+		"作業ログ,削除,DEMO-2,30003,2020-08-05T09:00:00.000+0900,,Bob,bob@example.com,4.00,0.00,-4.00\n" +
+		"作業ログ,追加,DEMO-3,30004,2020-08-31T17:00:00.000+0900,2020-09-02T10:00:00.000+0900,Alice,alice@example.com,0.00,1.00,1.00\n" +
+		"表示名,メールアドレス,アカウントID,変更前,変更後,差分\n" +
+		"Alice,alice@example.com,5b10a2844c20165700ede21g,8.00,11.00,3.00\n" +
+		"Bob,bob@example.com,5b10ac8d82e05b22cc7d4ef5,8.00,4.00,-4.00\n" +
+		"キー,概要,変更前,変更後,差分\n" +
+		"DEMO-1,ログイン画面の作成,12.00,14.00,2.00\n" +
+		"DEMO-2,ログイン画面の単体テスト,4.00,0.00,-4.00\n" +
+		"DEMO-3,パスワード再設定でエラーになる,0.00,1.00,1.00\n"
+	if actual := buf.String(); actual != expected {
+		t.Errorf("expected=[%v] <> actual[%v]\n", expected, actual)
+	}
+}
+
+func TestLoadExport(t *testing.T) {
+	saved := *config
+	defer func() { *config = saved }()
+	config.TargetYearMonth = "2020-08"
+	config.TimeUnit = "dd"
+	config.HoursPerDay = 8
+	config.Sites = ""
+
+	tests := []struct {
+		name     string
+		input    string
+		issues   int
+		worklogs int
+		changes  int
+	}{
+		{
+			name: "issues",
+			input: "キー,概要,ステータス,消費時間\n" +
+				"DEMO-1,ログイン画面の作成,Closed,1.50\n",
+			issues:  1,
+			changes: 1,
+		},
+		{
+			name: "worklogs",
+			input: "キー,開始日時,表示名,メールアドレス,アカウントID,消費時間\n" +
+				"DEMO-1,,,,,\n" +
+				"キー,開始日時,表示名,メールアドレス,アカウントID,消費時間\n" +
+				"DEMO-1,2020-08-03T09:00:00.000+0900,Alice,alice@example.com,5b10a2844c20165700ede21g,1.00\n",
+			issues:   1,
+			worklogs: 1,
+			changes:  0,
+		},
+	}
+	current := &Export{
+		Issues: Issues{{Key: "DEMO-1", Fields: IssueField{Summary: "ログイン画面の作成", Status: Status{Name: "Closed"}, Timespent: 28800}}},
+		Worklogs: Worklogs{{Key: "DEMO-1", Id: "30002", Started: "2020-08-03T09:00:00.000+0900", Timespentseconds: 28807,
+			Author: User{AccountId: "5b10a2844c20165700ede21g", Displayname: "Alice", Emailaddress: "alice@example.com"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export, err := LoadExport(strings.NewReader(tt.input), false)
+			if err != nil {
+				t.Fatalf("LoadExport() error = %v", err)
+			}
+			if len(export.Issues) != tt.issues || len(export.Worklogs) != tt.worklogs {
+				t.Errorf("expected=[%v,%v] <> actual[%v,%v]\n", tt.issues, tt.worklogs, len(export.Issues), len(export.Worklogs))
+			}
+
+			result := DiffExports(export, current)
+			if actual := len(result.Issues) + len(result.Worklogs); actual != tt.changes {
+				t.Errorf("expected=[%v] <> actual[%v]\n", tt.changes, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package jira
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type JqlParseResult struct {
+	Queries []struct {
+		Query  string   `json:"query"`
+		Errors []string `json:"errors"`
+	} `json:"queries"`
+}
+
+type DryRunResult struct {
+	Site         string
+	Name         string
+	Jql          string
+	Errors       []string
+	Total        int
+	SearchCalls  int
+	WorklogCalls int
+}
+
+func (r *DryRunResult) IsValid() bool {
+
+	return len(r.Errors) == 0
+}
+
+func IsDryRun() bool {
+
+	return config.DryRun
+}
+
+func getJqlParseResult(site Site, jql string) (*JqlParseResult, error) {
+
+	jqlParseURL, err := site.JqlParseURL()
+	if err != nil {
+		return nil, fmt.Errorf("site.JqlParseURL error: %v", err)
+	}
+
+	requestBody, err := json.Marshal(map[string]interface{}{"queries": []string{jql}})
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal error: %v\njql=[%v]", err, jql)
+	}
+
+	req, err := http.NewRequest("POST", jqlParseURL.String(), bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequest error: %v\njqlParseURL=[%v],requestBody=[%v]",
+			err, jqlParseURL, requestBody)
+	}
+
+	req.Header.Set("Authorization", site.basicAuthorization())
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	client := httpClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("client.Do error: %v\nreq=[%v]", err, req)
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ioutil.ReadAll error: %v\nresp.Body=[%v]", err, resp.Body)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %v\nresponseBody=[%v]", resp.Status, string(responseBody))
+	}
+
+	var result JqlParseResult
+	if err := json.Unmarshal(responseBody, &result); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error: %v\nresponseBody=[%v]", err, responseBody)
+	}
+
+	return &result, nil
+}
+
+func countIssues(site Site, jql string) (int, error) {
+
+	if site.enhancedSearch() {
+		count, err := getApproximateCount(site, jql)
+		if err != errSearchNotSupported || !site.fallbackSearch() {
+			return count, err
+		}
+	}
+
+	searchRequest := map[string]interface{}{
+		"fields":     []string{"key"},
+		"startAt":    0,
+		"maxResults": 0,
+	}
+	if len(jql) > 0 {
+		searchRequest["jql"] = jql
+	}
+
+	requestBody, err := json.Marshal(searchRequest)
+	if err != nil {
+		return 0, fmt.Errorf("json.Marshal error: %v\nsearchRequest=[%v]", err, searchRequest)
+	}
+
+	searchURL, err := site.SearchURL()
+	if err != nil {
+		return 0, fmt.Errorf("site.SearchURL error: %v", err)
+	}
+
+	result, err := getSearchResult(site, searchURL, requestBody)
+	if err != nil {
+		return 0, fmt.Errorf("getSearchResult error: %v\nrequestBody=[%v]", err, string(requestBody))
+	}
+
+	return result.Total, nil
+}
+
+func estimateCalls(total int, maxResult int, worklog bool) (int, int) {
+
+	searchCalls := 1
+	if maxResult > 0 && total > maxResult {
+		searchCalls = (total + maxResult - 1) / maxResult
+	}
+
+	worklogCalls := 0
+	if worklog {
+		worklogCalls = total
+	}
+
+	return searchCalls, worklogCalls
+}
+
+func DryRunSearch() ([]DryRunResult, []error) {
+
+	results := make([]DryRunResult, 0, 10)
+	dryRunErrors := make([]error, 0, 10)
+
+	sites, err := config.sites()
+	if err != nil {
+		return results, append(dryRunErrors, fmt.Errorf("config.sites error: %v", err))
+	}
+
+	for _, site := range sites {
+		for _, q := range config.namedQueries() {
+			result := DryRunResult{Site: site.Name, Name: q.Name}
+			jql, err := q.jql(site)
+			if err != nil {
+				dryRunErrors = append(dryRunErrors, fmt.Errorf("jql error: %v\nsite=[%v],name=[%v]", err, site.Name, q.Name))
+				results = append(results, result)
+				continue
+			}
+			result.Jql = jql
+			log.Printf("dry-run: site=[%v],name=[%v],query=[%v]\n", site.Name, q.Name, result.Jql)
+
+			parseResult, err := getJqlParseResult(site, result.Jql)
+			if err != nil {
+				dryRunErrors = append(dryRunErrors, fmt.Errorf("getJqlParseResult error: %v\nsite=[%v],name=[%v]", err, site.Name, q.Name))
+				results = append(results, result)
+				continue
+			}
+			for _, query := range parseResult.Queries {
+				result.Errors = append(result.Errors, query.Errors...)
+			}
+			if !result.IsValid() {
+				dryRunErrors = append(dryRunErrors, fmt.Errorf("invalid jql: %v\nsite=[%v],name=[%v],jql=[%v]", strings.Join(result.Errors, " "), site.Name, q.Name, result.Jql))
+			}
+
+			if result.IsValid() {
+				total, err := countIssues(site, result.Jql)
+				if err != nil {
+					dryRunErrors = append(dryRunErrors, fmt.Errorf("countIssues error: %v\nsite=[%v],name=[%v]", err, site.Name, q.Name))
+				}
+				result.Total = total
+				result.SearchCalls, result.WorklogCalls = estimateCalls(total, config.MaxResult, config.collectWorklog())
+			}
+
+			results = append(results, result)
+		}
+	}
+
+	return results, dryRunErrors
+}
+
+func RenderDryRunCsv(w io.Writer, results []DryRunResult) error {
+
+	writer := csv.NewWriter(w)
+	records := make([][]string, 0, 10)
+	records = append(records, []string{"サイト", "検索条件名", "JQL", "検証結果", "課題数", "検索API呼び出し数", "作業ログAPI呼び出し数"})
+
+	var total, searchCalls, worklogCalls int
+	for _, result := range results {
+		validation := "OK"
+		if !result.IsValid() {
+			validation = strings.Join(result.Errors, " ")
+		}
+		records = append(records, []string{
+			result.Site,
+			result.Name,
+			result.Jql,
+			validation,
+			strconv.Itoa(result.Total),
+			strconv.Itoa(result.SearchCalls),
+			strconv.Itoa(result.WorklogCalls),
+		})
+		total += result.Total
+		searchCalls += result.SearchCalls
+		worklogCalls += result.WorklogCalls
+	}
+	records = append(records, []string{"合計", "", "", "", strconv.Itoa(total), strconv.Itoa(searchCalls), strconv.Itoa(worklogCalls)})
+
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("writer.Write error: %v\nrecord=[%v]\n", err, record)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("writer.Error error: %v\n", err)
+	}
+
+	return nil
+}
+
+func DryRun(w io.Writer) []error {
+
+	results, dryRunErrors := DryRunSearch()
+	if err := RenderDryRunCsv(w, results); err != nil {
+		dryRunErrors = append(dryRunErrors, err)
+	}
+
+	return dryRunErrors
+}
@@ -0,0 +1,69 @@
+package jira
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEstimateCalls(t *testing.T) {
+	tests := []struct {
+		name         string
+		total        int
+		maxResult    int
+		worklog      bool
+		searchCalls  int
+		worklogCalls int
+	}{
+		{"empty", 0, 50, true, 1, 0},
+		{"one page", 50, 50, false, 1, 0},
+		{"three pages", 101, 50, false, 3, 0},
+		{"worklog", 120, 50, true, 3, 120},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			searchCalls, worklogCalls := estimateCalls(tt.total, tt.maxResult, tt.worklog)
+			if searchCalls != tt.searchCalls || worklogCalls != tt.worklogCalls {
+				t.Errorf("expected=[%v %v] <> actual[%v %v]\n", tt.searchCalls, tt.worklogCalls, searchCalls, worklogCalls)
+			}
+		})
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	server := setupFakeJira(t)
+	config.Queries = NamedQueries{{Name: "valid", Query: "project = DEMO"}, {Name: "invalid", Query: "project = (DEMO"}}
+
+	var buf bytes.Buffer
+	dryRunErrors := DryRun(&buf)
+	if len(dryRunErrors) != 1 || !strings.Contains(dryRunErrors[0].Error(), "name=[invalid]") {
+		t.Errorf("expected=[name=[invalid]] <> actual[%v]\n", dryRunErrors)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("csv.ReadAll() error = %v", err)
+	}
+	expected := [][]string{
+		{"valid", "OK", "3", "2", "0"},
+		{"invalid", "Error in the JQL Query: unbalanced parentheses.", "0", "0", "0"},
+		{"", "", "3", "2", "0"},
+	}
+	if len(records) != len(expected)+1 {
+		t.Fatalf("expected=[%v] <> actual[%v]\n", len(expected)+1, records)
+	}
+	for i, record := range records[1:] {
+		actual := append([]string{record[1]}, record[3:]...)
+		if !reflect.DeepEqual(expected[i], actual) {
+			t.Errorf("expected=[%v] <> actual[%v]\n", expected[i], actual)
+		}
+	}
+	if actual := server.Requests("jql/parse"); actual != 2 {
+		t.Errorf("expected=[%v] <> actual[%v]\n", 2, actual)
+	}
+	if actual := server.Requests("search/approximate-count"); actual != 1 {
+		t.Errorf("expected=[%v] <> actual[%v]\n", 1, actual)
+	}
+}
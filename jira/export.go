@@ -0,0 +1,207 @@
+package jira
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type Export struct {
+	Issues      Issues   `json:"issues"`
+	Worklogs    Worklogs `json:"worklogs"`
+	issueFields map[string]bool
+	noWorklogs  bool
+	rounded     bool
+}
+
+func NewExport(issues IssueSearchResults, worklogs WorklogResults) *Export {
+
+	export := &Export{Issues: Issues{}, Worklogs: Worklogs{}}
+	for _, result := range issues {
+		export.Issues = append(export.Issues, result.Issues...)
+	}
+	for _, result := range worklogs {
+		export.Worklogs = append(export.Worklogs, result.Worklogs...)
+	}
+
+	return export
+}
+
+func (e *Export) RenderJson(w io.Writer) error {
+
+	body, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json.MarshalIndent error: %v", err)
+	}
+
+	if _, err := w.Write(append(body, '\n')); err != nil {
+		return fmt.Errorf("w.Write error: %v", err)
+	}
+
+	return nil
+}
+
+func LoadExportFile(name string) (*Export, error) {
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open error: %v\nname=[%v]", err, name)
+	}
+	defer f.Close()
+
+	return LoadExport(f, strings.EqualFold(filepath.Ext(name), ".json"))
+}
+
+func LoadExport(r io.Reader, isJson bool) (*Export, error) {
+
+	if isJson {
+		body, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("ioutil.ReadAll error: %v", err)
+		}
+
+		var export Export
+		if err := json.Unmarshal(body, &export); err != nil {
+			return nil, fmt.Errorf("json.Unmarshal error: %v", err)
+		}
+		return &export, nil
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reader.ReadAll error: %v", err)
+	}
+
+	export := &Export{Issues: Issues{}, Worklogs: Worklogs{}, rounded: true}
+	var header map[string]int
+	worklogBlock := false
+	export.noWorklogs = true
+	for _, record := range records {
+		if len(record) > 0 && record[0] == "キー" {
+			header = map[string]int{}
+			for i, label := range record {
+				header[label] = i
+			}
+			_, worklogBlock = header[defaultFieldText["started"]]
+			if worklogBlock {
+				export.noWorklogs = false
+			}
+			if !worklogBlock && export.issueFields == nil {
+				export.issueFields = map[string]bool{}
+				for _, field := range []string{"summary", "status", "timespent"} {
+					_, export.issueFields[field] = header[defaultFieldText[field]]
+				}
+			}
+			continue
+		}
+		if header == nil {
+			return nil, fmt.Errorf("no header\nrecord=[%v]", record)
+		}
+
+		value := func(label string) string {
+			if i, ok := header[label]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		seconds, err := config.parseTime(value("消費時間"))
+		if err != nil {
+			return nil, fmt.Errorf("config.parseTime error: %v\nrecord=[%v]", err, record)
+		}
+
+		if !worklogBlock {
+			issue := Issue{Key: value("キー"), Site: value(defaultFieldText["site"])}
+			issue.Fields.Summary = value(defaultFieldText["summary"])
+			issue.Fields.Status.Name = value(defaultFieldText["status"])
+			issue.Fields.Timespent = seconds
+			export.Issues = append(export.Issues, issue)
+			continue
+		}
+
+		if len(value(defaultFieldText["started"])) == 0 {
+			export.Issues = append(export.Issues, Issue{Key: value("キー"), Site: value(defaultFieldText["site"])})
+			continue
+		}
+		export.Worklogs = append(export.Worklogs, WorklogField{
+			Key: value("キー"),
+			Author: User{
+				AccountId:    value(defaultFieldText["author.accountid"]),
+				Displayname:  value(defaultFieldText["author.displayname"]),
+				Emailaddress: value(defaultFieldText["author.emailaddress"]),
+			},
+			Started:          value(defaultFieldText["started"]),
+			Timespentseconds: seconds,
+			Site:             value(defaultFieldText["site"]),
+		})
+	}
+
+	if export.issueFields == nil {
+		export.issueFields = map[string]bool{}
+	}
+
+	return export, nil
+}
+
+func (e *Export) roundTimes() *Export {
+
+	export := *e
+	export.Issues = make(Issues, len(e.Issues))
+	for i, issue := range e.Issues {
+		issue.Fields.Timespent = config.roundTime(issue.Fields.Timespent)
+		export.Issues[i] = issue
+	}
+	export.Worklogs = make(Worklogs, len(e.Worklogs))
+	for i, worklog := range e.Worklogs {
+		worklog.Timespentseconds = config.roundTime(worklog.Timespentseconds)
+		export.Worklogs[i] = worklog
+	}
+
+	return &export
+}
+
+func (e *Export) hasIssueField(field string) bool {
+
+	if e.issueFields == nil {
+		return true
+	}
+
+	return e.issueFields[field]
+}
+
+func (c *Config) parseTime(value string) (int, error) {
+
+	if len(value) == 0 {
+		return 0, nil
+	}
+
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("strconv.ParseFloat error: %v\nvalue=[%v]", err, value)
+	}
+
+	unit := c.WithTimeUnit(60 * 60)
+	if unit == 0 {
+		return 0, fmt.Errorf("unknown time unit: %v", c.TimeUnit)
+	}
+
+	return int(math.Round(v / unit * 60 * 60)), nil
+}
+
+func (c *Config) roundTime(second int) int {
+
+	rounded, err := c.parseTime(c.FormatTime(second))
+	if err != nil {
+		return second
+	}
+
+	return rounded
+}
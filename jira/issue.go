@@ -2,19 +2,21 @@ package jira
 
 import (
 	"bytes"
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"reflect"
 	"sort"
 	"strings"
 	"sync"
 )
 
+var searchSlots = make(chan struct{}, maxWorkerSize)
+
 func (a Issues) Len() int {
 
 	return len(a)
@@ -27,13 +29,34 @@ func (a Issues) Swap(i, j int) {
 
 func (a Issues) Less(i, j int) bool {
 
+	if a[i].Key == a[j].Key {
+		return a[i].Site < a[j].Site
+	}
+
 	return a[i].Key < a[j].Key
 }
 
+func (i *Issue) siteKey() string {
+
+	if len(i.Site) == 0 {
+		return i.Key
+	}
+
+	return fmt.Sprintf("%s/%s", i.Site, i.Key)
+}
+
 func (i *Issue) ToRecord(fields []string) []string {
 
 	result := []string{i.Key}
 	result = append(result, i.Fields.ToRecord(fields)...)
+	for n, fieldName := range fields {
+		switch fieldName {
+		case "query":
+			result[n+1] = strings.Join(i.QueryNames, ",")
+		case "site":
+			result[n+1] = i.Site
+		}
+	}
 	return result
 }
 
@@ -50,9 +73,17 @@ func (f *IssueField) ToRecord(fields []string) []string {
 			switch fieldName {
 			case "timespent", "timeoriginalestimate", "aggregatetimespent", "aggregatetimeoriginalestimate":
 				second := int(field.Int())
-				v = fmt.Sprintf("%.2f", config.WithTimeUnit(second))
+				v = config.FormatTime(second)
 			case "status":
 				v = f.Status.Name
+			case "issuetype":
+				v = f.Issuetype.Name
+			case "project":
+				v = f.Project.Key
+			case "parent":
+				if f.Parent != nil {
+					v = f.Parent.Key
+				}
 			default:
 				switch field.Kind() {
 				case reflect.String:
@@ -78,32 +109,64 @@ func (r *IssueSearchResult) IsNotEmpty() bool {
 
 func (r *IssueSearchResult) RestPages() []int {
 
+	pages := make([]int, 0, 10)
+	if r.MaxResults <= 0 {
+		return pages
+	}
+
 	current := r.StartAt/r.MaxResults + 1
 	next := current + 1
-	last := r.Total/r.MaxResults + 1
+	last := (r.Total + r.MaxResults - 1) / r.MaxResults
 
-	pages := make([]int, 0, 10)
 	for page := next; page <= last; page++ {
 		pages = append(pages, page)
 	}
 	return pages
 }
 
-func (results IssueSearchResults) RenderCsv(w io.Writer, fields []string) error {
+func (results IssueSearchResults) missingOffsets(total int) [][2]int {
 
-	fieldLabels := []string{"キー"}
+	pages := make(IssueSearchResults, len(results))
+	copy(pages, results)
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].StartAt < pages[j].StartAt
+	})
+
+	missing := make([][2]int, 0, 2)
+	offset := 0
+	for _, page := range pages {
+		if page.StartAt > offset {
+			missing = append(missing, [2]int{offset, page.StartAt})
+		}
+		if end := page.StartAt + len(page.Issues); end > offset {
+			offset = end
+		}
+	}
+	if offset < total {
+		missing = append(missing, [2]int{offset, total})
+	}
+
+	return missing
+}
+
+func fieldLabels(fields []string) []string {
+
+	labels := []string{"キー"}
 	for _, field := range fields {
 		label := field
 		if text, ok := defaultFieldText[label]; ok {
 			label = text
 		}
-		fieldLabels = append(fieldLabels, label)
-	}
-	writer := csv.NewWriter(w)
-	if err := writer.Write(fieldLabels); err != nil {
-		return fmt.Errorf("writer.Write error: %v\nfieldLabels=[%v]\n", err, fieldLabels)
+		labels = append(labels, label)
 	}
 
+	return labels
+}
+
+func (results IssueSearchResults) Table(fields []string) Table {
+
+	table := newTable(fieldLabels(fields)...)
+
 	allIssues := make(Issues, 0, 10)
 	for _, result := range results {
 		allIssues = append(allIssues, result.Issues...)
@@ -111,93 +174,86 @@ func (results IssueSearchResults) RenderCsv(w io.Writer, fields []string) error
 	sort.Sort(allIssues)
 
 	for _, issue := range allIssues {
-		record := issue.ToRecord(fields)
-		if err := writer.Write(record); err != nil {
-			return fmt.Errorf("writer.Write error: %v\nrecord=[%v]\n", err, record)
-		}
+		table.append(issue.ToRecord(fields))
 	}
 
-	writer.Flush()
-	if err := writer.Error(); err != nil {
-		return fmt.Errorf("writer.Error error: %v\n", err)
-	}
+	return table
+}
+
+func (results IssueSearchResults) RenderCsv(w io.Writer, fields []string) error {
 
-	return nil
+	return Tables{results.Table(fields)}.RenderCsv(w)
 }
 
-func getFilterJql(filterID string) (string, bool) {
+func getFilterJql(site Site, filterID string) (string, error) {
 
-	cacheKey := fmt.Sprintf("getFilterJql_%s", filterID)
+	cacheKey := site.cacheKey(fmt.Sprintf("getFilterJql_%s", filterID))
 	if v, ok := cache.get(cacheKey); ok {
 		log.Printf("cache hit: key=[%s], v=[%v]\n", cacheKey, v)
-		return v.(string), true
+		return v.(string), nil
 	}
 
-	filterURL, err := config.FilterURL(filterID)
+	filterURL, err := site.FilterURL(filterID)
 	if err != nil {
-		log.Printf("config.FilterURL error: %v\nfilterID=[%v]\n", err, filterID)
-		return "", false
+		return "", fmt.Errorf("site.FilterURL error: %v\nfilterID=[%v]", err, filterID)
 	}
 
 	req, err := http.NewRequest("GET", filterURL.String(), nil)
 	if err != nil {
-		log.Printf("http.NewRequest error: %v\nfilterURL=[%v]\n", err, filterURL)
-		return "", false
+		return "", fmt.Errorf("http.NewRequest error: %v\nfilterURL=[%v]", err, filterURL)
 	}
 
-	req.Header.Set("Authorization", config.basicAuthorization())
+	req.Header.Set("Authorization", site.basicAuthorization())
 	req.Header.Set("Accept", "application/json")
 
-	client := &http.Client{}
+	client := httpClient()
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Printf("client.Do error: %v\nreq=[%v]\n", err, req)
-		return "", false
+		return "", fmt.Errorf("client.Do error: %v\nreq=[%v]", err, req)
 	}
 	defer resp.Body.Close()
 
 	responseBody, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.Printf("ioutil.ReadAll error: %v\nresp.Body=[%v]\n", err, resp.Body)
-		return "", false
+		return "", fmt.Errorf("ioutil.ReadAll error: %v\nresp.Body=[%v]", err, resp.Body)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status: %v\nfilterID=[%v],responseBody=[%v]", resp.Status, filterID, string(responseBody))
 	}
 	var result struct {
 		Jql string `json:"jql"`
 	}
 	if err := json.Unmarshal(responseBody, &result); err != nil {
-		log.Printf("json.Unmarshal error: %v\nresponseBody=[%v]\n", err, responseBody)
-		return "", false
+		return "", fmt.Errorf("json.Unmarshal error: %v\nresponseBody=[%v]", err, responseBody)
 	}
 
 	cache.put(cacheKey, result.Jql)
-	return result.Jql, true
+	return result.Jql, nil
 }
 
-func getSearchResult(requestBody []byte) (*IssueSearchResult, error) {
+func getSearchResult(site Site, searchURL *url.URL, requestBody []byte) (*IssueSearchResult, error) {
 
-	cacheKey := fmt.Sprintf("getSearchResult_%s", string(requestBody))
+	cacheKey := site.cacheKey(fmt.Sprintf("getSearchResult_%s_%s", searchURL.Path, string(requestBody)))
 	if v, ok := cache.get(cacheKey); ok {
 		log.Printf("cache hit: key=[%s], v=[%v]\n", cacheKey, v)
 		result := v.(IssueSearchResult)
 		return &result, nil
 	}
 
-	searchURL, err := config.SearchURL()
-	if err != nil {
-		return nil, fmt.Errorf("config.SearchURL error: %v", err)
-	}
-
 	req, err := http.NewRequest("POST", searchURL.String(), bytes.NewBuffer(requestBody))
 	if err != nil {
 		return nil, fmt.Errorf("http.NewRequest error: %v\nsearchURL=[%v],requestBody=[%v]",
 			err, searchURL, requestBody)
 	}
 
-	req.Header.Set("Authorization", config.basicAuthorization())
+	req.Header.Set("Authorization", site.basicAuthorization())
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 
-	client := &http.Client{}
+	searchSlots <- struct{}{}
+	defer func() { <-searchSlots }()
+
+	client := httpClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("client.Do error: %v\nreq=[%v]", err, req)
@@ -209,6 +265,12 @@ func getSearchResult(requestBody []byte) (*IssueSearchResult, error) {
 	if err != nil {
 		return nil, fmt.Errorf("ioutil.ReadAll error: %v\nresp.Body=[%v]", err, resp.Body)
 	}
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return nil, errSearchNotSupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %v\nresponseBody=[%v]", resp.Status, string(responseBody))
+	}
 	if err := json.Unmarshal(responseBody, &result); err != nil {
 		return nil, fmt.Errorf("json.Unmarshal error: %v\nresponseBody=[%v]", err, responseBody)
 	}
@@ -217,7 +279,7 @@ func getSearchResult(requestBody []byte) (*IssueSearchResult, error) {
 	return &result, nil
 }
 
-func searchCh(pages []int, issuesPerPage int) (<-chan *IssueSearchResult, <-chan error) {
+func searchCh(site Site, jql string, pages []int, issuesPerPage int) (<-chan *IssueSearchResult, <-chan error) {
 
 	resultCh := make(chan *IssueSearchResult, len(pages))
 	defer close(resultCh)
@@ -237,7 +299,7 @@ func searchCh(pages []int, issuesPerPage int) (<-chan *IssueSearchResult, <-chan
 	wg.Add(workerSize)
 	startAtCh := make(chan int, len(pages))
 	for n := 0; n < workerSize; n++ {
-		go searchWorker(n, &wg, startAtCh, resultCh, errorCh)
+		go searchWorker(n, site, jql, issuesPerPage, &wg, startAtCh, resultCh, errorCh)
 	}
 
 	for _, page := range pages {
@@ -249,11 +311,11 @@ func searchCh(pages []int, issuesPerPage int) (<-chan *IssueSearchResult, <-chan
 	return resultCh, errorCh
 }
 
-func searchWorker(n int, wg *sync.WaitGroup, startAtCh <-chan int, resultCh chan<- *IssueSearchResult, errorCh chan<- error) {
+func searchWorker(n int, site Site, jql string, issuesPerPage int, wg *sync.WaitGroup, startAtCh <-chan int, resultCh chan<- *IssueSearchResult, errorCh chan<- error) {
 
 	defer wg.Done()
 	for startAt := range startAtCh {
-		result, err := search(startAt)
+		result, err := search(site, jql, startAt, issuesPerPage)
 		if err != nil {
 			errorCh <- fmt.Errorf("search error: %v\nn=[%v],startAt=[%v]", err, n, startAt)
 		}
@@ -264,61 +326,103 @@ func searchWorker(n int, wg *sync.WaitGroup, startAtCh <-chan int, resultCh chan
 	}
 }
 
-func search(startAt int) (*IssueSearchResult, error) {
+func search(site Site, jql string, startAt int, maxResult int) (*IssueSearchResult, error) {
 
 	searchRequest := map[string]interface{}{
-		"fields":     config.fields(),
+		"fields":     config.searchFields(),
 		"startAt":    startAt,
-		"maxResults": config.MaxResult,
-	}
-	if len(config.Query) > 0 {
-		searchRequest["jql"] = config.Query
+		"maxResults": maxResult,
 	}
-	if len(config.TargetYearMonth) > 0 {
-		if dateCondition, ok := config.dateCondition(); ok {
-			searchRequest["jql"] = composeJql(searchRequest["jql"].(string), dateCondition)
-		}
+	if config.expandChangelog() {
+		searchRequest["expand"] = []string{"changelog"}
 	}
-	if len(config.Filter) > 0 {
-		if filterQuery, ok := getFilterJql(config.Filter); ok {
-			searchRequest["jql"] = filterQuery
-		}
+	if len(jql) > 0 {
+		searchRequest["jql"] = jql
 	}
 
-	log.Printf("search: startAt=[%v],query=[%v]\n", startAt, searchRequest["jql"])
+	log.Printf("search: site=[%v],startAt=[%v],query=[%v]\n", site.Name, startAt, searchRequest["jql"])
 	requestBody, err := json.Marshal(searchRequest)
 	if err != nil {
 		return nil, fmt.Errorf("json.Marshal error: %v\nsearchRequest=[%v]", err, searchRequest)
 	}
-	result, err := getSearchResult(requestBody)
+	searchURL, err := site.SearchURL()
+	if err != nil {
+		return nil, fmt.Errorf("site.SearchURL error: %v", err)
+	}
+
+	result, err := getSearchResult(site, searchURL, requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("getSearchResult error: %v\nrequestBody=[%v]", err, string(requestBody))
 	}
 
-	if result.IsNotEmpty() {
-		return result, nil
+	for i := range result.Issues {
+		result.Issues[i].Site = site.Name
 	}
+	config.progress.page(site.Name, len(result.Issues))
+	config.worklogStream.add(result.Issues)
 
-	return nil, fmt.Errorf("empty result")
+	return result, nil
 }
 
-func composeJql(baseQuery string, condition string) string {
-	if len(condition) == 0 {
-		return baseQuery
+func issueSearch(site Site, q NamedQuery, maxResult int) (IssueSearchResults, []error) {
+
+	results := make(IssueSearchResults, 0, 10)
+	searchErrors := make([]error, 0, 10)
+
+	jql, err := q.jql(site)
+	if err != nil {
+		return results, append(searchErrors, fmt.Errorf("jql error: %v", err))
+	}
+	if site.enhancedSearch() {
+		results, searchErrors, ok := enhancedIssueSearch(site, jql, maxResult)
+		if ok {
+			return results, searchErrors
+		}
+		log.Printf("enhanced search is not supported, fall back to legacy search: site=[%v]\n", site.Name)
+	}
+
+	firstResult, err := search(site, jql, 0, maxResult)
+	if err != nil {
+		return results, append(searchErrors, fmt.Errorf("search error: %v\nstartAt=[%v]", err, 0))
+	}
+	if firstResult.IsNotEmpty() {
+		results = append(results, *firstResult)
 	}
 
-	if strings.Contains(strings.ToLower(baseQuery), "worklogdate") {
-		return baseQuery
+	issuesPerPage := firstResult.MaxResults
+	if issuesPerPage <= 0 {
+		issuesPerPage = len(firstResult.Issues)
 	}
+	if issuesPerPage <= 0 {
+		return results, searchErrors
+	}
+	firstResult.MaxResults = issuesPerPage
 
-	if strings.Contains(strings.ToLower(baseQuery), "updated") {
-		return baseQuery
+	resultCh, errorCh := searchCh(site, jql, firstResult.RestPages(), issuesPerPage)
+	for err := range errorCh {
+		searchErrors = append(searchErrors, err)
+	}
+	for result := range resultCh {
+		if result.IsNotEmpty() {
+			results = append(results, *result)
+		}
 	}
 
-	if strings.Contains(strings.ToLower(baseQuery), "order by") {
-		i := strings.Index(strings.ToLower(baseQuery), "order by")
-		return fmt.Sprintf("%s AND (%s) %s", baseQuery[0:i], condition, baseQuery[i:])
+	for _, missing := range results.missingOffsets(firstResult.Total) {
+		for startAt := missing[0]; startAt < missing[1]; {
+			log.Printf("search missing issues: site=[%v],startAt=[%v]\n", site.Name, startAt)
+			result, err := search(site, jql, startAt, issuesPerPage)
+			if err != nil {
+				searchErrors = append(searchErrors, fmt.Errorf("search error: %v\nstartAt=[%v]", err, startAt))
+				break
+			}
+			if !result.IsNotEmpty() {
+				break
+			}
+			results = append(results, *result)
+			startAt += len(result.Issues)
+		}
 	}
 
-	return fmt.Sprintf("%s AND (%s)", baseQuery, condition)
+	return results, searchErrors
 }
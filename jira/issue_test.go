@@ -1,7 +1,12 @@
 package jira
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -33,3 +38,112 @@ func TestToRecord(t *testing.T) {
 		t.Errorf("expected=[%v] <> actual[%v]\n", expected, actual)
 	}
 }
+
+type fakeSearchServer struct {
+	total     int
+	capacity  int
+	truncated map[int]int
+	requests  []int
+	mutex     sync.Mutex
+}
+
+func (f *fakeSearchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		StartAt    int `json:"startAt"`
+		MaxResults int `json:"maxResults"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.mutex.Lock()
+	f.requests = append(f.requests, request.StartAt)
+	f.mutex.Unlock()
+
+	maxResults := request.MaxResults
+	if f.capacity > 0 && maxResults > f.capacity {
+		maxResults = f.capacity
+	}
+	size := maxResults
+	if n, ok := f.truncated[request.StartAt]; ok {
+		size = n
+	}
+
+	result := IssueSearchResult{StartAt: request.StartAt, MaxResults: maxResults, Total: f.total, Issues: Issues{}}
+	for i := request.StartAt; i < request.StartAt+size && i < f.total; i++ {
+		result.Issues = append(result.Issues, Issue{Key: fmt.Sprintf("TEST-%d", i+1)})
+	}
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func TestIssueSearch_pagination(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxResult int
+		server    *fakeSearchServer
+		requests  int
+	}{
+		{"no issue", 10, &fakeSearchServer{total: 0}, 1},
+		{"one issue", 10, &fakeSearchServer{total: 1}, 1},
+		{"exact multiple of page size", 10, &fakeSearchServer{total: 30}, 3},
+		{"one more than page size", 10, &fakeSearchServer{total: 11}, 2},
+		{"smaller page size from server", 50, &fakeSearchServer{total: 45, capacity: 20}, 3},
+		{"truncated page", 10, &fakeSearchServer{total: 30, truncated: map[int]int{10: 7}}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.Handle("/rest/api/2/search", tt.server)
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			site := Site{BaseURL: server.URL, ApiVersion: "2", User: "user", Token: "token", SearchApi: searchApiLegacy}
+			results, searchErrors := issueSearch(site, NamedQuery{Query: tt.name}, tt.maxResult)
+			if len(searchErrors) > 0 {
+				t.Fatalf("issueSearch() errors = %v", searchErrors)
+			}
+
+			actual := map[string]bool{}
+			for _, result := range mergeResults(NamedQueries{{}}, []IssueSearchResults{results}) {
+				for _, issue := range result.Issues {
+					actual[issue.Key] = true
+				}
+			}
+			expected := map[string]bool{}
+			for i := 1; i <= tt.server.total; i++ {
+				expected[fmt.Sprintf("TEST-%d", i)] = true
+			}
+			if !reflect.DeepEqual(expected, actual) {
+				t.Errorf("expected=[%v] <> actual[%v]\n", expected, actual)
+			}
+
+			if len(tt.server.requests) != tt.requests {
+				t.Errorf("expected=[%v] <> actual[%v]\n", tt.requests, tt.server.requests)
+			}
+		})
+	}
+}
+
+func TestIssueSearchResult_RestPages(t *testing.T) {
+	tests := []struct {
+		name   string
+		result IssueSearchResult
+		want   []int
+	}{
+		{"empty", IssueSearchResult{Total: 0, MaxResults: 50}, []int{}},
+		{"single page", IssueSearchResult{Total: 50, MaxResults: 50}, []int{}},
+		{"exact multiple", IssueSearchResult{Total: 100, MaxResults: 50}, []int{2}},
+		{"remainder", IssueSearchResult{Total: 101, MaxResults: 50}, []int{2, 3}},
+		{"zero page size", IssueSearchResult{Total: 10, MaxResults: 0}, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := tt.result.RestPages(); !reflect.DeepEqual(tt.want, actual) {
+				t.Errorf("expected=[%v] <> actual[%v]\n", tt.want, actual)
+			}
+		})
+	}
+}
@@ -5,50 +5,202 @@ import (
 	"fmt"
 	"io"
 	"net/url"
+	"sync"
 )
 
+var configMutex sync.Mutex
+
 func init() {
 	flag.Usage = func() {
 		fmt.Printf(usageText, version)
 		flag.PrintDefaults()
 	}
 	flag.StringVar(&config.BaseURL, "url", "https://your-jira.atlassian.net", "jira url")
+	flag.StringVar(&config.Sites, "sites", "", "site definition file (yaml), overrides -url and -api")
+	flag.StringVar(&config.AuthorMap, "author-map", "", "file of author mapping across sites (accountId or emailAddress,emailAddress per line)")
 	flag.StringVar(&config.Query, "query", "status = Closed AND updated >= startOfMonth(-1) AND updated <= endOfMonth(-1)", "jira query language expression")
 	flag.StringVar(&config.Filter, "filter", "", "jira search filter id")
+	flag.Var(&namedQueryFlag{queries: &config.Queries}, "named-query", "named jira query (name=JQL), can be repeated")
+	flag.Var(&namedQueryFlag{queries: &config.Queries, filter: true}, "named-filter", "named jira search filter id (name=id), can be repeated")
+	flag.StringVar(&config.Projects, "project", "", "comma separated project keys added to the query")
+	flag.StringVar(&config.Assignees, "assignee", "", "comma separated assignees added to the query")
+	flag.StringVar(&config.Statuses, "status", "", "comma separated statuses added to the query")
+	flag.StringVar(&config.IssueTypes, "issuetype", "", "comma separated issue types added to the query")
+	flag.StringVar(&config.Labels, "label", "", "comma separated labels added to the query")
+	flag.StringVar(&config.WorklogAuthors, "worklog-author", "", "comma separated worklog authors added to the query")
 	flag.StringVar(&config.FieldNames, "fields", "summary,status,timespent,timeoriginalestimate,aggregatetimespent,aggregatetimeoriginalestimate", "fields of jira issue")
 	flag.IntVar(&config.MaxResult, "maxresult", defaultMaxResult, "max result for pagination")
+	flag.IntVar(&config.MaxRetry, "max-retry", defaultMaxRetry, "number of retries of rate limited (429) or unavailable (503) jira requests")
 	flag.StringVar(&config.ApiVersion, "api", defaultJiraRestApiVersion, "number of API Version of Jira REST API")
+	flag.StringVar(&config.SearchApi, "search-api", defaultSearchApi, "issue search endpoint (auto: search/jql with fallback to search, jql, legacy)")
 	flag.StringVar(&config.TimeUnit, "unit", "dd", "time unit format string")
 	flag.IntVar(&config.HoursPerDay, "hours", defaultHoursPerDay, "work hours per day")
-	flag.IntVar(&config.DaysPerMonth, "days", defaultDaysPerMonth, "work days per month")
+	flag.IntVar(&config.DaysPerMonth, "days", defaultDaysPerMonth, "work days per month of the mm unit (0: count working days of target month)")
+	flag.StringVar(&config.Holidays, "holidays", "", "file of company holidays (yyyy-MM-dd[,name] per line)")
 	flag.BoolVar(&config.Worklog, "worklog", false, "collect worklog toggle")
+	flag.BoolVar(&config.Demo, "demo", false, "run against a built-in fake jira with sample data")
+	flag.StringVar(&config.Record, "record", "", "directory to save every jira response")
+	flag.StringVar(&config.Replay, "replay", "", "directory of recorded jira responses used instead of jira")
+	flag.StringVar(&config.Database, "db", defaultDatabase, "sqlite database file of the sync and report -from-db commands")
+	flag.BoolVar(&config.FromDb, "from-db", false, "report from the database instead of jira")
+	flag.IntVar(&config.Snapshot, "snapshot", 0, "snapshot id of the database used by -from-db (0: latest)")
+	flag.StringVar(&config.DiffFrom, "diff-from", "", "snapshot id or saved report file (csv, json) compared by the diff command (default: previous snapshot of -diff-to)")
+	flag.StringVar(&config.DiffTo, "diff-to", "", "snapshot id or saved report file (csv, json) compared by the diff command (default: latest snapshot)")
+	flag.StringVar(&config.Schedules, "schedules", "", "schedule definition file (yaml) of the server")
+	flag.StringVar(&config.SmtpHost, "smtp-host", "", "smtp server (host:port) of the mail destination")
+	flag.StringVar(&config.SmtpFrom, "smtp-from", "", "from address of the mail destination")
+	flag.BoolVar(&config.SmtpStartTLS, "smtp-starttls", true, "require STARTTLS of the smtp server (false: use it only if the server supports it)")
+	flag.StringVar(&config.MailTo, "mail-to", "", "comma separated mail addresses the report is sent to")
+	flag.StringVar(&config.MailSubject, "mail-subject", defaultMailSubject, "mail subject template ({{.Name}}: report type or schedule name, {{.TargetMonth}}: target month)")
+	flag.StringVar(&config.SlackWebhook, "slack-webhook", "", "slack incoming webhook url the report summary is posted to")
+	flag.StringVar(&config.TeamsWebhook, "teams-webhook", "", "teams incoming webhook url the report summary is posted to")
+	flag.StringVar(&config.NotifyTemplate, "notify-template", "", "message template file (go text/template) of the report summary")
+	flag.StringVar(&config.NotifyLink, "notify-link", "", "url of the full report added to the report summary")
+	flag.BoolVar(&config.DryRun, "dry-run", false, "print and validate the composed queries without fetching worklogs")
 	flag.StringVar(&config.TargetYearMonth, "targetym", "", "target year month(yyyy-MM)")
+	flag.StringVar(&config.Report, "report", defaultReport, "report type (timespent, status, timesheet, compliance, cost, team, json)")
+	flag.StringVar(&config.Roster, "roster", "", "file of expected members (emailAddress[,displayName] per line)")
+	flag.Float64Var(&config.MinHours, "min-hours", 0, "minimum logged hours per working day (0: same as -hours)")
+	flag.Float64Var(&config.MaxHours, "max-hours", 0, "maximum logged hours per day (0: unlimited)")
+	flag.StringVar(&config.RateCard, "ratecard", "", "rate card file (json)")
+	flag.StringVar(&config.Users, "users", "", "file of user attributes (accountId,employeeId,team,costCentre per line)")
+	flag.StringVar(&config.Teams, "teams", "", "team definition file (yaml)")
+	flag.StringVar(&config.TeamGroups, "team-groups", "", "comma separated jira groups treated as teams")
+	flag.StringVar(&config.TeamAllocation, "team-allocation", defaultAllocation, "allocation rule for members of multiple teams (first, split, all)")
+	flag.StringVar(&config.InvoiceBy, "invoice-by", defaultInvoiceBy, "invoice grouping (project, epic)")
+	flag.StringVar(&config.RoundMode, "round", defaultRoundMode, "rounding mode (entry: round each row and sum them, total: round totals only)")
+	flag.StringVar(&config.RoundMethod, "round-method", defaultRoundMethod, "rounding method (nearest, up, down)")
+	flag.Float64Var(&config.RoundIncrement, "round-increment", 0, "rounding increment in time unit (e.g. 0.25)")
+	flag.IntVar(&config.Precision, "precision", defaultPrecision, "number of decimal places")
 }
 
 func SetFlags() {
 	flag.Parse()
+	if flag.NArg() > 0 {
+		config.Command = flag.Arg(0)
+		if err := flag.CommandLine.Parse(flag.Args()[1:]); err != nil {
+			panic(err)
+		}
+	}
+
+	if err := config.validateCommand(); err != nil {
+		panic(err)
+	}
+
+	if config.Demo {
+		if err := startDemo(); err != nil {
+			panic(err)
+		}
+	}
+
+	if len(config.Replay) > 0 {
+		if err := config.startReplay(); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	if config.offline() {
+		return
+	}
+
+	if len(config.Record) > 0 {
+		if err := config.startRecord(); err != nil {
+			panic(err)
+		}
+	}
 
 	if err := config.checkAuthEnv(); err != nil {
 		panic(err)
 	}
 }
 
-func SetQueryParams(queryParams url.Values) {
+func SetQueryParams(queryParams url.Values) error {
+
+	return config.SetQueryParams(queryParams)
+}
+
+func ValidateQueryParams(queryParams url.Values) error {
+
+	var c Config
+	return c.SetQueryParams(queryParams)
+}
+
+func WithQueryParams(queryParams url.Values, f func()) error {
+
+	return withConfig(func(c *Config) error {
+		return c.SetQueryParams(queryParams)
+	}, f)
+}
+
+func withConfig(apply func(c *Config) error, f func()) error {
+
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	saved := *config
+	defer func() { *config = saved }()
+
+	if err := apply(config); err != nil {
+		return err
+	}
+	f()
+
+	return nil
+}
+
+func currentConfig() Config {
+
+	configMutex.Lock()
+	defer configMutex.Unlock()
 
-	config.SetQueryParams(queryParams)
+	return *config
 }
 
 func Search() (IssueSearchResults, WorklogResults, []error) {
 
+	if config.FromDb {
+		return LoadFromStore()
+	}
+
+	defer config.progress.done()
+
+	if err := config.validateSearchApi(); err != nil {
+		config.progress.errors([]error{err})
+		return IssueSearchResults{}, WorklogResults{}, []error{err}
+	}
+
+	if config.collectWorklog() {
+		config.worklogStream = newWorklogStream()
+		defer func() { config.worklogStream = nil }()
+	}
+
 	issues, searchErrors := IssueSearch(config.MaxResult)
-	if !config.Worklog {
+	config.progress.errors(searchErrors)
+	config.progress.issues(issues)
+	if config.expandChangelog() {
+		changelogErrors := ChangelogSearch(issues)
+		config.progress.errors(changelogErrors)
+		searchErrors = append(searchErrors, changelogErrors...)
+	}
+
+	if !config.collectWorklog() {
 		var nothing WorklogResults
 		return issues, nothing, searchErrors
 	}
 
-	worklogs, worklogErrors := WorklogSearch(issues)
+	worklogs, worklogErrors := config.worklogStream.wait(issues)
+	config.progress.errors(worklogErrors)
 	searchErrors = append(searchErrors, worklogErrors...)
 
+	resolveErrors := ResolveAuthors(worklogs)
+	config.progress.errors(resolveErrors)
+	searchErrors = append(searchErrors, resolveErrors...)
+	if err := ReconcileAuthors(worklogs); err != nil {
+		config.progress.errors([]error{err})
+		searchErrors = append(searchErrors, err)
+	}
+
 	return issues, worklogs, searchErrors
 }
 
@@ -56,62 +208,91 @@ func Report(w io.Writer, issues IssueSearchResults, worklogs WorklogResults) []e
 
 	renderErrors := make([]error, 0, 2)
 
-	if issues != nil {
-		if err := issues.RenderCsv(w, config.fields()); err != nil {
+	if config.Report == reportJson {
+		if err := config.validateReport(); err != nil {
+			return append(renderErrors, err)
+		}
+		if err := NewExport(issues, worklogs).RenderJson(w); err != nil {
 			renderErrors = append(renderErrors, err)
 		}
+		return renderErrors
 	}
 
-	if worklogs != nil {
-		if err := worklogs.RenderCsv(w, config.fields()); err != nil {
-			renderErrors = append(renderErrors, err)
-		}
+	tables, renderErrors := ReportTables(issues, worklogs)
+	if err := tables.RenderCsv(w); err != nil {
+		renderErrors = append(renderErrors, err)
 	}
 
 	return renderErrors
 }
 
-func IssueSearch(maxResult int) (IssueSearchResults, []error) {
+func ReportTables(issues IssueSearchResults, worklogs WorklogResults) (Tables, []error) {
 
-	results := make(IssueSearchResults, 0, 10)
-	searchErrors := make([]error, 0, 10)
+	renderErrors := make([]error, 0, 2)
 
-	resultCh, errorCh := searchCh([]int{1}, maxResult)
-	if err := <-errorCh; err != nil {
-		searchErrors = append(searchErrors, err)
+	if err := config.validateReport(); err != nil {
+		return nil, append(renderErrors, err)
 	}
-	firstResult := <-resultCh
 
-	if firstResult != nil {
-		if firstResult.IsNotEmpty() {
-			results = append(results, *firstResult)
-		}
+	var tables Tables
+	var err error
+	switch config.Report {
+	case "", defaultReport:
+	case "status":
+		tables, err = issues.StatusTables()
+	case "timesheet":
+		tables, err = worklogs.TimesheetTables()
+	case "compliance":
+		tables, err = worklogs.ComplianceTables()
+	case "cost":
+		tables, err = CostTables(issues, worklogs)
+	case "team":
+		tables, err = worklogs.TeamTables()
+	default:
+		err = fmt.Errorf("unknown report type: %v", config.Report)
+	}
+	if err != nil {
+		return nil, append(renderErrors, err)
+	}
+	if tables != nil {
+		return tables, renderErrors
+	}
 
-		resultCh, errorCh := searchCh(firstResult.RestPages(), firstResult.MaxResults)
-		for err := range errorCh {
-			searchErrors = append(searchErrors, err)
-		}
-		for result := range resultCh {
-			results = append(results, *result)
+	tables = make(Tables, 0, 2)
+	if issues != nil {
+		tables = append(tables, issues.Table(config.fields()))
+	}
+
+	if worklogs != nil {
+		table, err := worklogs.Table(config.fields())
+		if err != nil {
+			renderErrors = append(renderErrors, err)
+		} else {
+			tables = append(tables, *table)
 		}
 	}
 
-	return results, searchErrors
+	return tables, renderErrors
 }
 
-func WorklogSearch(results IssueSearchResults) (WorklogResults, []error) {
-
-	worklogResults := make(WorklogResults, 0, 10)
-	searchErrors := make([]error, 0, 10)
+func (c *Config) validateReport() error {
 
-	worklogCh, errorCh := worklogCh(results)
-	for err := range errorCh {
-		searchErrors = append(searchErrors, err)
+	if err := c.validateRounding(); err != nil {
+		return err
+	}
+	if _, err := c.calendar(); err != nil {
+		return err
 	}
 
-	for worklog := range worklogCh {
-		worklogResults = append(worklogResults, *worklog)
+	return nil
+}
+
+func WorklogSearch(results IssueSearchResults) (WorklogResults, []error) {
+
+	stream := newWorklogStream()
+	for _, result := range results {
+		stream.add(result.Issues)
 	}
 
-	return worklogResults, searchErrors
+	return stream.wait(results)
 }
@@ -0,0 +1,166 @@
+package jira
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"bitbucket.org/yujiorama/jira-timespent-report/jira/jiratest"
+)
+
+func setupFakeJira(t *testing.T) *jiratest.Server {
+	server, err := jiratest.NewServer()
+	if err != nil {
+		t.Fatalf("jiratest.NewServer() error = %v", err)
+	}
+
+	saved := *config
+	user, token := os.Getenv("AUTH_USER"), os.Getenv("AUTH_TOKEN")
+	t.Cleanup(func() {
+		server.Close()
+		*config = saved
+		os.Setenv("AUTH_USER", user)
+		os.Setenv("AUTH_TOKEN", token)
+	})
+
+	os.Setenv("AUTH_USER", server.User)
+	os.Setenv("AUTH_TOKEN", server.Token)
+	*config = Config{
+		BaseURL:         server.URL,
+		Query:           "project = DEMO",
+		FieldNames:      "summary,timespent",
+		MaxResult:       2,
+		ApiVersion:      "3",
+		SearchApi:       searchApiAuto,
+		TimeUnit:        "hh",
+		HoursPerDay:     8,
+		TargetYearMonth: "2020-08",
+		Report:          defaultReport,
+		TeamAllocation:  defaultAllocation,
+		RoundMode:       defaultRoundMode,
+		RoundMethod:     defaultRoundMethod,
+		Precision:       defaultPrecision,
+		clock:           saved.clock,
+	}
+
+	return server
+}
+
+func searchAndReport(t *testing.T) string {
+	issues, worklogs, searchErrors := Search()
+	if len(searchErrors) > 0 {
+		t.Fatalf("Search() errors = %v", searchErrors)
+	}
+
+	var buf bytes.Buffer
+	if reportErrors := Report(&buf, issues, worklogs); len(reportErrors) > 0 {
+		t.Fatalf("Report() errors = %v", reportErrors)
+	}
+
+	return buf.String()
+}
+
+func TestSearchAndReport(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(server *jiratest.Server)
+		output string
+	}{
+		{
+			name:  "issues",
+			setup: func(server *jiratest.Server) {},
+			output: "キー,概要,消費時間\n" +
+				"DEMO-1,ログイン画面の作成,16.00\n" +
+				"DEMO-2,ログイン画面の単体テスト,4.00\n" +
+				"DEMO-3,パスワード再設定でエラーになる,3.00\n",
+		},
+		{
+			name: "worklogs with legacy search",
+			setup: func(server *jiratest.Server) {
+				config.Worklog = true
+				config.SearchApi = searchApiLegacy
+			},
+			output: "キー,開始日時,表示名,メールアドレス,アカウントID,消費時間\n" +
+				"DEMO-1,,,,,\n" +
+				"DEMO-2,,,,,\n" +
+				"DEMO-3,,,,,\n" +
+				"キー,開始日時,表示名,メールアドレス,アカウントID,消費時間\n" +
+				"DEMO-1,2020-08-03T09:00:00.000+0900,Alice,alice@example.com,5b10a2844c20165700ede21g,8.00\n" +
+				"DEMO-1,2020-08-04T13:00:00.000+0900,Bob,bob@example.com,5b10ac8d82e05b22cc7d4ef5,4.00\n" +
+				"DEMO-2,2020-08-05T09:00:00.000+0900,Bob,bob@example.com,5b10ac8d82e05b22cc7d4ef5,4.00\n" +
+				"DEMO-3,2020-08-17T10:00:00.000+0900,Alice,alice@example.com,5b10a2844c20165700ede21g,3.00\n",
+		},
+		{
+			name: "timesheet of named filters",
+			setup: func(server *jiratest.Server) {
+				config.Report = "timesheet"
+				config.Queries = NamedQueries{{Name: "demo", Filter: "10000"}, {Name: "ops", Filter: "10001"}}
+			},
+			output: "検索条件名,表示名,メールアドレス,アカウントID,消費時間,所定時間,差分\n" +
+				"demo,Alice,alice@example.com,5b10a2844c20165700ede21g,11.00,160.00,-149.00\n" +
+				"demo,Bob,bob@example.com,5b10ac8d82e05b22cc7d4ef5,8.00,160.00,-152.00\n" +
+				"demo,小計,,,19.00,,\n" +
+				"ops,Bob,bob@example.com,5b10ac8d82e05b22cc7d4ef5,6.00,160.00,-154.00\n" +
+				"ops,小計,,,6.00,,\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := setupFakeJira(t)
+			tt.setup(server)
+
+			if actual := searchAndReport(t); actual != tt.output {
+				t.Errorf("expected=[%v] <> actual[%v]\n", tt.output, actual)
+			}
+		})
+	}
+}
+
+func TestSearch_errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(server *jiratest.Server)
+	}{
+		{
+			name: "too many requests",
+			setup: func(server *jiratest.Server) {
+				server.FailNext(1)
+			},
+		},
+		{
+			name: "unauthorized",
+			setup: func(server *jiratest.Server) {
+				server.Token = "invalid"
+			},
+		},
+		{
+			name: "missing author map",
+			setup: func(server *jiratest.Server) {
+				config.Worklog = true
+				config.AuthorMap = filepath.Join(t.TempDir(), "missing.csv")
+			},
+		},
+		{
+			name: "invalid author map",
+			setup: func(server *jiratest.Server) {
+				config.Worklog = true
+				config.AuthorMap = filepath.Join(t.TempDir(), "author-map.csv")
+				if err := ioutil.WriteFile(config.AuthorMap, []byte("alice@example.com\n"), 0644); err != nil {
+					t.Fatalf("ioutil.WriteFile() error = %v", err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := setupFakeJira(t)
+			tt.setup(server)
+
+			if _, _, searchErrors := Search(); len(searchErrors) == 0 {
+				t.Errorf("Search() errors = nil, want errors")
+			}
+		})
+	}
+}
@@ -0,0 +1,572 @@
+package jiratest
+
+import (
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultUser     = "demo@example.com"
+	DefaultToken    = "demo-token"
+	defaultPageSize = 50
+)
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+var (
+	apiPathPattern = regexp.MustCompile(`^/rest/api/([^/]+)/(.+)$`)
+	projectPattern = regexp.MustCompile(`(?i)\bproject\s*(?:=\s*"?([^"\s)]+)"?|in\s*\(([^)]*)\))`)
+)
+
+type fixtureIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Project struct {
+			Key string `json:"key"`
+		} `json:"project"`
+	} `json:"fields"`
+	Changelog struct {
+		Histories []json.RawMessage `json:"histories"`
+	} `json:"changelog"`
+}
+
+type fixtureWorklog struct {
+	Started string `json:"started"`
+}
+
+type Server struct {
+	*httptest.Server
+	User        string
+	Token       string
+	Latency     time.Duration
+	MaxPageSize int
+
+	mutex           sync.Mutex
+	tooManyRequests int
+	requests        map[string]int
+	inFlight        int
+	maxInFlight     int
+
+	issues    []json.RawMessage
+	issueKeys []fixtureIssue
+	worklogs  map[string][]json.RawMessage
+	users     []json.RawMessage
+	groups    map[string][]string
+	filters   map[string]string
+	fields    json.RawMessage
+}
+
+func NewServer() (*Server, error) {
+
+	s, err := NewUnstartedServer()
+	if err != nil {
+		return nil, err
+	}
+
+	s.Start()
+	return s, nil
+}
+
+func NewUnstartedServer() (*Server, error) {
+
+	s := &Server{
+		User:        DefaultUser,
+		Token:       DefaultToken,
+		MaxPageSize: defaultPageSize,
+		requests:    map[string]int{},
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(s.serveHTTP))
+	return s, nil
+}
+
+func (s *Server) load() error {
+
+	for name, v := range map[string]interface{}{
+		"fixtures/issues.json":   &s.issues,
+		"fixtures/worklogs.json": &s.worklogs,
+		"fixtures/users.json":    &s.users,
+		"fixtures/groups.json":   &s.groups,
+		"fixtures/filters.json":  &s.filters,
+		"fixtures/fields.json":   &s.fields,
+	} {
+		body, err := fixtures.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("fixtures.ReadFile error: %v\nname=[%v]", err, name)
+		}
+		if err := json.Unmarshal(body, v); err != nil {
+			return fmt.Errorf("json.Unmarshal error: %v\nname=[%v]", err, name)
+		}
+	}
+
+	s.issueKeys = make([]fixtureIssue, len(s.issues))
+	for i, issue := range s.issues {
+		if err := json.Unmarshal(issue, &s.issueKeys[i]); err != nil {
+			return fmt.Errorf("json.Unmarshal error: %v\nissue=[%v]", err, string(issue))
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) FailNext(n int) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.tooManyRequests = n
+}
+
+func (s *Server) Requests(endpoint string) int {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests[endpoint]
+}
+
+func (s *Server) MaxConcurrentRequests() int {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.maxInFlight
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+
+	s.mutex.Lock()
+	s.inFlight++
+	if s.inFlight > s.maxInFlight {
+		s.maxInFlight = s.inFlight
+	}
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		s.inFlight--
+		s.mutex.Unlock()
+	}()
+
+	if s.Latency > 0 {
+		time.Sleep(s.Latency)
+	}
+
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "認証情報が正しくありません")
+		return
+	}
+
+	m := apiPathPattern.FindStringSubmatch(r.URL.Path)
+	if m == nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	path := strings.Split(m[2], "/")
+	endpoint := path[0]
+	if len(path) == 3 && path[0] == "issue" {
+		endpoint = "issue/" + path[2]
+	} else if len(path) == 2 && path[0] != "filter" {
+		endpoint = m[2]
+	}
+
+	s.mutex.Lock()
+	s.requests[endpoint]++
+	tooManyRequests := s.tooManyRequests > 0
+	if tooManyRequests {
+		s.tooManyRequests--
+	}
+	s.mutex.Unlock()
+
+	if tooManyRequests {
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+		return
+	}
+
+	switch {
+	case endpoint == "search" && r.Method == http.MethodPost:
+		s.search(w, r)
+	case endpoint == "search/jql" && r.Method == http.MethodPost:
+		s.searchJql(w, r)
+	case endpoint == "search/approximate-count" && r.Method == http.MethodPost:
+		s.approximateCount(w, r)
+	case endpoint == "jql/parse" && r.Method == http.MethodPost:
+		s.parseJql(w, r)
+	case endpoint == "filter" && len(path) == 2:
+		s.filter(w, path[1])
+	case endpoint == "issue/worklog":
+		s.worklog(w, r, path[1])
+	case endpoint == "issue/changelog":
+		s.changelog(w, r, path[1])
+	case endpoint == "user":
+		s.user(w, r)
+	case endpoint == "group/member":
+		s.groupMember(w, r)
+	case endpoint == "field":
+		writeJSON(w, s.fields)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+
+	expected := "Basic " + base64.URLEncoding.EncodeToString([]byte(s.User+":"+s.Token))
+	return r.Header.Get("Authorization") == expected
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"errorMessages": []string{message}})
+}
+
+func (s *Server) pageSize(maxResults int) int {
+
+	if maxResults <= 0 || (s.MaxPageSize > 0 && maxResults > s.MaxPageSize) {
+		return s.MaxPageSize
+	}
+
+	return maxResults
+}
+
+func projects(jql string) map[string]bool {
+
+	m := projectPattern.FindStringSubmatch(jql)
+	if m == nil {
+		return nil
+	}
+
+	keys := map[string]bool{}
+	values := m[1]
+	if len(m[2]) > 0 {
+		values = m[2]
+	}
+	for _, value := range strings.Split(values, ",") {
+		keys[strings.ToUpper(strings.Trim(strings.TrimSpace(value), `"'`))] = true
+	}
+
+	return keys
+}
+
+func (s *Server) matchedIssues(jql string, expand bool) []json.RawMessage {
+
+	keys := projects(jql)
+
+	issues := make([]json.RawMessage, 0, len(s.issues))
+	for i, issue := range s.issues {
+		if keys != nil && !keys[s.issueKeys[i].Fields.Project.Key] {
+			continue
+		}
+		if !expand {
+			var v map[string]json.RawMessage
+			if err := json.Unmarshal(issue, &v); err == nil {
+				delete(v, "changelog")
+				issue, _ = json.Marshal(v)
+			}
+		}
+		issues = append(issues, issue)
+	}
+
+	return issues
+}
+
+func expandsChangelog(expand interface{}) bool {
+
+	switch v := expand.(type) {
+	case string:
+		return strings.Contains(v, "changelog")
+	case []interface{}:
+		for _, e := range v {
+			if e == "changelog" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+
+	var request struct {
+		Jql        string      `json:"jql"`
+		StartAt    int         `json:"startAt"`
+		MaxResults int         `json:"maxResults"`
+		Expand     interface{} `json:"expand"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	issues := s.matchedIssues(request.Jql, expandsChangelog(request.Expand))
+	pageSize := s.pageSize(request.MaxResults)
+	if request.MaxResults == 0 {
+		pageSize = 0
+	}
+
+	page := make([]json.RawMessage, 0, pageSize)
+	for i := request.StartAt; i < request.StartAt+pageSize && i < len(issues); i++ {
+		page = append(page, issues[i])
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"startAt":    request.StartAt,
+		"maxResults": pageSize,
+		"total":      len(issues),
+		"issues":     page,
+	})
+}
+
+func (s *Server) searchJql(w http.ResponseWriter, r *http.Request) {
+
+	var request struct {
+		Jql           string      `json:"jql"`
+		NextPageToken string      `json:"nextPageToken"`
+		MaxResults    int         `json:"maxResults"`
+		Expand        interface{} `json:"expand"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	startAt := 0
+	if len(request.NextPageToken) > 0 {
+		n, err := strconv.Atoi(request.NextPageToken)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid nextPageToken")
+			return
+		}
+		startAt = n
+	}
+
+	issues := s.matchedIssues(request.Jql, expandsChangelog(request.Expand))
+	pageSize := s.pageSize(request.MaxResults)
+
+	page := make([]json.RawMessage, 0, pageSize)
+	for i := startAt; i < startAt+pageSize && i < len(issues); i++ {
+		page = append(page, issues[i])
+	}
+
+	response := map[string]interface{}{
+		"issues": page,
+		"isLast": startAt+pageSize >= len(issues),
+	}
+	if startAt+pageSize < len(issues) {
+		response["nextPageToken"] = strconv.Itoa(startAt + pageSize)
+	}
+
+	writeJSON(w, response)
+}
+
+func (s *Server) approximateCount(w http.ResponseWriter, r *http.Request) {
+
+	var request struct {
+		Jql string `json:"jql"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"count": len(s.matchedIssues(request.Jql, false))})
+}
+
+func (s *Server) parseJql(w http.ResponseWriter, r *http.Request) {
+
+	var request struct {
+		Queries []string `json:"queries"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	type parsedQuery struct {
+		Query  string   `json:"query"`
+		Errors []string `json:"errors,omitempty"`
+	}
+	queries := make([]parsedQuery, 0, len(request.Queries))
+	for _, query := range request.Queries {
+		parsed := parsedQuery{Query: query}
+		if strings.Count(query, "(") != strings.Count(query, ")") {
+			parsed.Errors = append(parsed.Errors, "Error in the JQL Query: unbalanced parentheses.")
+		}
+		if strings.Count(query, `"`)%2 != 0 {
+			parsed.Errors = append(parsed.Errors, "Error in the JQL Query: unterminated quote.")
+		}
+		queries = append(queries, parsed)
+	}
+
+	writeJSON(w, map[string]interface{}{"queries": queries})
+}
+
+func (s *Server) filter(w http.ResponseWriter, id string) {
+
+	jql, ok := s.filters[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "filter not found")
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"id": id, "jql": jql})
+}
+
+func queryInt(r *http.Request, name string, defaultValue int) int {
+
+	if n, err := strconv.Atoi(r.URL.Query().Get(name)); err == nil {
+		return n
+	}
+
+	return defaultValue
+}
+
+func (s *Server) worklog(w http.ResponseWriter, r *http.Request, key string) {
+
+	worklogs, ok := s.worklogs[key]
+	if !ok {
+		if !s.hasIssue(key) {
+			writeError(w, http.StatusNotFound, "issue not found")
+			return
+		}
+	}
+
+	startedAfter := int64(queryInt(r, "startedAfter", 0))
+	matched := make([]json.RawMessage, 0, len(worklogs))
+	for _, worklog := range worklogs {
+		var v fixtureWorklog
+		if err := json.Unmarshal(worklog, &v); err != nil {
+			continue
+		}
+		started, err := time.Parse("2006-01-02T15:04:05.000-0700", v.Started)
+		if err != nil || started.UnixNano()/int64(time.Millisecond) < startedAfter {
+			continue
+		}
+		matched = append(matched, worklog)
+	}
+
+	startAt := queryInt(r, "startAt", 0)
+	maxResults := queryInt(r, "maxResults", len(matched))
+	page := make([]json.RawMessage, 0, len(matched))
+	for i := startAt; i < startAt+maxResults && i < len(matched); i++ {
+		page = append(page, matched[i])
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"startAt":    startAt,
+		"maxResults": maxResults,
+		"total":      len(matched),
+		"worklogs":   page,
+	})
+}
+
+func (s *Server) hasIssue(key string) bool {
+
+	for _, issue := range s.issueKeys {
+		if issue.Key == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Server) changelog(w http.ResponseWriter, r *http.Request, key string) {
+
+	for _, issue := range s.issueKeys {
+		if issue.Key != key {
+			continue
+		}
+
+		histories := issue.Changelog.Histories
+		startAt := queryInt(r, "startAt", 0)
+		maxResults := s.pageSize(queryInt(r, "maxResults", 0))
+		page := make([]json.RawMessage, 0, maxResults)
+		for i := startAt; i < startAt+maxResults && i < len(histories); i++ {
+			page = append(page, histories[i])
+		}
+
+		writeJSON(w, map[string]interface{}{
+			"startAt":    startAt,
+			"maxResults": maxResults,
+			"total":      len(histories),
+			"isLast":     startAt+maxResults >= len(histories),
+			"values":     page,
+		})
+		return
+	}
+
+	writeError(w, http.StatusNotFound, "issue not found")
+}
+
+func (s *Server) user(w http.ResponseWriter, r *http.Request) {
+
+	accountId := r.URL.Query().Get("accountId")
+	for _, user := range s.users {
+		var v struct {
+			AccountId string `json:"accountId"`
+		}
+		if err := json.Unmarshal(user, &v); err == nil && v.AccountId == accountId {
+			writeJSON(w, user)
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "user not found")
+}
+
+func (s *Server) groupMember(w http.ResponseWriter, r *http.Request) {
+
+	accountIds, ok := s.groups[r.URL.Query().Get("groupname")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "group not found")
+		return
+	}
+	sort.Strings(accountIds)
+
+	members := make([]json.RawMessage, 0, len(accountIds))
+	for _, accountId := range accountIds {
+		for _, user := range s.users {
+			if strings.Contains(string(user), `"`+accountId+`"`) {
+				members = append(members, user)
+			}
+		}
+	}
+
+	startAt := queryInt(r, "startAt", 0)
+	maxResults := s.pageSize(queryInt(r, "maxResults", 0))
+	page := make([]json.RawMessage, 0, maxResults)
+	for i := startAt; i < startAt+maxResults && i < len(members); i++ {
+		page = append(page, members[i])
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"startAt":    startAt,
+		"maxResults": maxResults,
+		"total":      len(members),
+		"isLast":     startAt+maxResults >= len(members),
+		"values":     page,
+	})
+}
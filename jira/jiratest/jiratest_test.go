@@ -0,0 +1,76 @@
+package jiratest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func post(t *testing.T, s *Server, path string, body interface{}, token string) (*http.Response, map[string]interface{}) {
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	req, err := http.NewRequest("POST", s.URL+path, bytes.NewBuffer(requestBody))
+	if err != nil {
+		t.Fatalf("http.NewRequest() error = %v", err)
+	}
+	req.Header.Set("Authorization", "Basic "+base64.URLEncoding.EncodeToString([]byte(s.User+":"+token)))
+
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatalf("client.Do() error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	return resp, result
+}
+
+func TestServer(t *testing.T) {
+	s, err := NewServer()
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer s.Close()
+
+	resp, result := post(t, s, "/rest/api/3/search", map[string]interface{}{"jql": "project = DEMO", "startAt": 2, "maxResults": 2}, s.Token)
+	if resp.StatusCode != http.StatusOK || result["total"] != 3.0 || len(result["issues"].([]interface{})) != 1 {
+		t.Errorf("expected=[%v] <> actual[%v]\n", "total=3,issues=1", result)
+	}
+
+	resp, result = post(t, s, "/rest/api/3/search/jql", map[string]interface{}{"jql": "project IN (DEMO, OPS)", "maxResults": 3}, s.Token)
+	if resp.StatusCode != http.StatusOK || result["nextPageToken"] != "3" || result["isLast"] != false {
+		t.Errorf("expected=[%v] <> actual[%v]\n", "nextPageToken=3", result)
+	}
+
+	resp, _ = post(t, s, "/rest/api/3/search", map[string]interface{}{}, "invalid")
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected=[%v] <> actual[%v]\n", http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	s.FailNext(1)
+	resp, _ = post(t, s, "/rest/api/3/search", map[string]interface{}{}, s.Token)
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("expected=[%v] <> actual[%v]\n", http.StatusTooManyRequests, resp.StatusCode)
+	}
+
+	s.Latency = 50 * time.Millisecond
+	start := time.Now()
+	post(t, s, "/rest/api/3/search/approximate-count", map[string]interface{}{"jql": "project = OPS"}, s.Token)
+	if elapsed := time.Since(start); elapsed < s.Latency {
+		t.Errorf("expected=[%v] <> actual[%v]\n", s.Latency, elapsed)
+	}
+
+	if actual := s.Requests("search"); actual != 2 {
+		t.Errorf("expected=[%v] <> actual[%v]\n", 2, actual)
+	}
+}
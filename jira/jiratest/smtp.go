@@ -0,0 +1,168 @@
+package jiratest
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+type Mail struct {
+	From string
+	To   []string
+	Data string
+	User string
+}
+
+type SMTPServer struct {
+	Addr     string
+	User     string
+	Password string
+
+	listener net.Listener
+	mutex    sync.Mutex
+	mails    []Mail
+	wg       sync.WaitGroup
+}
+
+func NewSMTPServer() (*SMTPServer, error) {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("net.Listen error: %v", err)
+	}
+
+	s := &SMTPServer{Addr: listener.Addr().String(), listener: listener}
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+func (s *SMTPServer) Close() {
+
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *SMTPServer) Mails() []Mail {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Mail{}, s.mails...)
+}
+
+func (s *SMTPServer) serve() {
+
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.session(textproto.NewConn(conn))
+		}()
+	}
+}
+
+func (s *SMTPServer) session(conn *textproto.Conn) {
+
+	var mail Mail
+	conn.PrintfLine("220 jiratest ESMTP")
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command, arg := line, ""
+		if i := strings.Index(line, " "); i >= 0 {
+			command, arg = line[:i], line[i+1:]
+		}
+
+		switch strings.ToUpper(command) {
+		case "EHLO", "HELO":
+			if len(s.User) > 0 {
+				conn.PrintfLine("250-jiratest")
+				conn.PrintfLine("250 AUTH PLAIN")
+			} else {
+				conn.PrintfLine("250 jiratest")
+			}
+		case "AUTH":
+			user, ok := s.auth(arg)
+			if !ok {
+				conn.PrintfLine("535 authentication failed")
+				continue
+			}
+			mail.User = user
+			conn.PrintfLine("235 authenticated")
+		case "MAIL":
+			if len(s.User) > 0 && len(mail.User) == 0 {
+				conn.PrintfLine("530 authentication required")
+				continue
+			}
+			mail.From = address(arg)
+			conn.PrintfLine("250 ok")
+		case "RCPT":
+			mail.To = append(mail.To, address(arg))
+			conn.PrintfLine("250 ok")
+		case "DATA":
+			conn.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+			data, err := conn.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			mail.Data = string(data)
+			s.mutex.Lock()
+			s.mails = append(s.mails, mail)
+			s.mutex.Unlock()
+			mail = Mail{User: mail.User}
+			conn.PrintfLine("250 ok")
+		case "RSET", "NOOP":
+			conn.PrintfLine("250 ok")
+		case "QUIT":
+			conn.PrintfLine("221 bye")
+			return
+		default:
+			conn.PrintfLine("502 command not implemented")
+		}
+	}
+}
+
+func (s *SMTPServer) auth(arg string) (string, bool) {
+
+	fields := strings.Fields(arg)
+	if len(fields) != 2 || strings.ToUpper(fields[0]) != "PLAIN" {
+		return "", false
+	}
+
+	credentials, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return "", false
+	}
+
+	parts := strings.Split(string(credentials), "\x00")
+	if len(parts) != 3 || parts[1] != s.User || parts[2] != s.Password {
+		return "", false
+	}
+
+	return parts[1], true
+}
+
+func address(arg string) string {
+
+	if i := strings.Index(arg, ":"); i >= 0 {
+		arg = arg[i+1:]
+	}
+	if i := strings.Index(arg, " "); i >= 0 {
+		arg = arg[:i]
+	}
+
+	return strings.Trim(arg, "<>")
+}
@@ -0,0 +1,214 @@
+package jira
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	jqlWord = iota
+	jqlString
+	jqlOperator
+)
+
+type jqlToken struct {
+	kind int
+	text string
+	pos  int
+}
+
+func tokenizeJql(jql string) []jqlToken {
+
+	tokens := make([]jqlToken, 0, 10)
+	for i := 0; i < len(jql); {
+		c := jql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '"' || c == '\'':
+			start := i
+			for i++; i < len(jql) && jql[i] != c; i++ {
+				if jql[i] == '\\' {
+					i++
+				}
+			}
+			if i < len(jql) {
+				i++
+			}
+			if i > len(jql) {
+				i = len(jql)
+			}
+			tokens = append(tokens, jqlToken{kind: jqlString, text: jql[start:i], pos: start})
+		case strings.IndexByte("=!<>~(),", c) >= 0:
+			start := i
+			i++
+			if i < len(jql) && (c == '!' || c == '<' || c == '>') && (jql[i] == '=' || jql[i] == '~') {
+				i++
+			}
+			tokens = append(tokens, jqlToken{kind: jqlOperator, text: jql[start:i], pos: start})
+		default:
+			start := i
+			for i < len(jql) && strings.IndexByte(" \t\r\n\"'=!<>~(),", jql[i]) < 0 {
+				i++
+			}
+			tokens = append(tokens, jqlToken{kind: jqlWord, text: jql[start:i], pos: start})
+		}
+	}
+
+	return tokens
+}
+
+func (t jqlToken) isWord(words ...string) bool {
+
+	if t.kind != jqlWord {
+		return false
+	}
+
+	for _, word := range words {
+		if strings.EqualFold(t.text, word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t jqlToken) isComparison() bool {
+
+	switch {
+	case t.kind == jqlOperator:
+		return t.text != "(" && t.text != ")" && t.text != ","
+	case t.isWord("in", "not", "is", "was", "changed"):
+		return true
+	}
+
+	return false
+}
+
+func splitOrderBy(jql string) (string, string) {
+
+	tokens := tokenizeJql(jql)
+	depth := 0
+	for i, token := range tokens {
+		switch {
+		case token.kind == jqlOperator && token.text == "(":
+			depth++
+		case token.kind == jqlOperator && token.text == ")":
+			depth--
+		case depth == 0 && token.isWord("order") && i+1 < len(tokens) && tokens[i+1].isWord("by"):
+			return strings.TrimSpace(jql[:token.pos]), strings.TrimSpace(jql[token.pos:])
+		}
+	}
+
+	return strings.TrimSpace(jql), ""
+}
+
+func hasJqlField(jql string, names ...string) bool {
+
+	tokens := tokenizeJql(jql)
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].isWord(names...) && tokens[i+1].isComparison() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasTopLevelOr(jql string) bool {
+
+	depth := 0
+	for _, token := range tokenizeJql(jql) {
+		switch {
+		case token.kind == jqlOperator && token.text == "(":
+			depth++
+		case token.kind == jqlOperator && token.text == ")":
+			depth--
+		case depth == 0 && token.isWord("or"):
+			return true
+		}
+	}
+
+	return false
+}
+
+func appendJqlCondition(jql string, condition string) string {
+
+	if len(condition) == 0 {
+		return jql
+	}
+
+	where, orderBy := splitOrderBy(jql)
+	switch {
+	case len(where) == 0:
+		where = condition
+	case hasTopLevelOr(where):
+		where = fmt.Sprintf("(%s) AND (%s)", where, condition)
+	default:
+		where = fmt.Sprintf("%s AND (%s)", where, condition)
+	}
+
+	if len(orderBy) > 0 {
+		return fmt.Sprintf("%s %s", where, orderBy)
+	}
+
+	return where
+}
+
+func composeJql(baseQuery string, condition string) string {
+
+	if hasJqlField(baseQuery, "updated", "worklogDate") {
+		return baseQuery
+	}
+
+	return appendJqlCondition(baseQuery, condition)
+}
+
+func quoteJql(value string) string {
+
+	if strings.HasSuffix(value, "()") && len(tokenizeJql(value)) == 3 {
+		return value
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return fmt.Sprintf(`"%s"`, replacer.Replace(value))
+}
+
+func jqlClause(field string, values string) string {
+
+	quoted := make([]string, 0, 5)
+	for _, value := range strings.Split(values, ",") {
+		value = strings.TrimSpace(value)
+		if len(value) > 0 {
+			quoted = append(quoted, quoteJql(value))
+		}
+	}
+
+	switch len(quoted) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("%s = %s", field, quoted[0])
+	default:
+		return fmt.Sprintf("%s IN (%s)", field, strings.Join(quoted, ", "))
+	}
+}
+
+func (c *Config) builderJql() string {
+
+	clauses := make([]string, 0, 6)
+	for _, clause := range []string{
+		jqlClause("project", c.Projects),
+		jqlClause("assignee", c.Assignees),
+		jqlClause("status", c.Statuses),
+		jqlClause("issuetype", c.IssueTypes),
+		jqlClause("labels", c.Labels),
+		jqlClause("worklogAuthor", c.WorklogAuthors),
+	} {
+		if len(clause) > 0 {
+			clauses = append(clauses, clause)
+		}
+	}
+
+	return strings.Join(clauses, " AND ")
+}
@@ -0,0 +1,72 @@
+package jira
+
+import (
+	"testing"
+)
+
+func TestComposeJql(t *testing.T) {
+	condition := "updated >= startOfMonth(-1) AND updated <= endOfMonth(-1)"
+	tests := []struct {
+		name      string
+		baseQuery string
+		want      string
+	}{
+		{
+			name:      "append",
+			baseQuery: "status = Closed",
+			want:      "status = Closed AND (" + condition + ")",
+		},
+		{
+			name:      "empty",
+			baseQuery: "",
+			want:      condition,
+		},
+		{
+			name:      "order by",
+			baseQuery: "status = Closed order by key DESC",
+			want:      "status = Closed AND (" + condition + ") order by key DESC",
+		},
+		{
+			name:      "or",
+			baseQuery: "project = A OR project = B ORDER BY key",
+			want:      "(project = A OR project = B) AND (" + condition + ") ORDER BY key",
+		},
+		{
+			name:      "updated clause",
+			baseQuery: "status = Closed AND updated >= -30d",
+			want:      "status = Closed AND updated >= -30d",
+		},
+		{
+			name:      "worklogDate clause",
+			baseQuery: "worklogDate >= startOfMonth()",
+			want:      "worklogDate >= startOfMonth()",
+		},
+		{
+			name:      "updated in text",
+			baseQuery: `summary ~ "updated" AND text ~ "order by"`,
+			want:      `summary ~ "updated" AND text ~ "order by" AND (` + condition + ")",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := composeJql(tt.baseQuery, condition); actual != tt.want {
+				t.Errorf("expected=[%v] <> actual[%v]\n", tt.want, actual)
+			}
+		})
+	}
+}
+
+func TestConfig_builderJql(t *testing.T) {
+	c := &Config{
+		Projects:       "ALPHA, BETA",
+		Assignees:      "currentUser()",
+		Statuses:       "In Progress",
+		Labels:         `say "hi"`,
+		WorklogAuthors: `alice\bob`,
+	}
+
+	expected := `project IN ("ALPHA", "BETA") AND assignee = currentUser() AND status = "In Progress" AND labels = "say \"hi\"" AND worklogAuthor = "alice\\bob"`
+	if actual := c.builderJql(); actual != expected {
+		t.Errorf("expected=[%v] <> actual[%v]\n", expected, actual)
+	}
+}
@@ -0,0 +1,408 @@
+package jira
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type PeriodLock struct {
+	TargetMonth string
+	LockedAt    time.Time
+	Checksum    string
+	Export      *Export
+}
+
+type LockCheck struct {
+	Lock     PeriodLock
+	Checksum string
+	Result   *DiffResult
+}
+
+type LateChangeError struct {
+	TargetMonth string
+	Changes     int
+}
+
+func (e *LateChangeError) Error() string {
+
+	return fmt.Sprintf("late changes in locked period: targetMonth=[%v],changes=[%v]", e.TargetMonth, e.Changes)
+}
+
+func IsLateChange(err error) bool {
+
+	var lateChange *LateChangeError
+	return errors.As(err, &lateChange)
+}
+
+func (c *LockCheck) IsChanged() bool {
+
+	return c.Checksum != c.Lock.Checksum || len(c.Result.Worklogs) > 0
+}
+
+func (c *Config) targetMonthText() (string, error) {
+
+	target, err := c.TargetMonth()
+	if err != nil {
+		return "", err
+	}
+
+	return target.Format("2006-01"), nil
+}
+
+func lockChecksum(worklogs Worklogs) string {
+
+	totals := map[string]int{}
+	for _, worklog := range worklogs {
+		totals[worklog.siteKey()+"\t"+worklog.authorKey()] += worklog.Timespentseconds
+	}
+
+	lines := make([]string, 0, len(totals))
+	for key, second := range totals {
+		lines = append(lines, fmt.Sprintf("%s\t%d\n", key, second))
+	}
+	sort.Strings(lines)
+
+	hash := sha256.New()
+	for _, line := range lines {
+		hash.Write([]byte(line))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func lockExport(issues IssueSearchResults, worklogs WorklogResults) *Export {
+
+	export := &Export{Issues: Issues{}, Worklogs: worklogs.inTargetMonth()}
+
+	keys := map[string]bool{}
+	for _, worklog := range export.Worklogs {
+		keys[worklog.siteKey()] = true
+	}
+	for _, result := range issues {
+		for _, issue := range result.Issues {
+			if keys[issue.siteKey()] {
+				export.Issues = append(export.Issues, Issue{Key: issue.Key, Site: issue.Site, Fields: IssueField{Summary: issue.Fields.Summary}})
+				delete(keys, issue.siteKey())
+			}
+		}
+	}
+	sort.Sort(export.Issues)
+
+	return export
+}
+
+func (s *Store) lock(targetMonth string) (*PeriodLock, error) {
+
+	var lockedAt, checksum, content string
+	err := s.db.QueryRow("SELECT locked_at, checksum, content FROM locks WHERE target_month = ?", targetMonth).Scan(&lockedAt, &checksum, &content)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db.QueryRow error: %v\ntargetMonth=[%v]", err, targetMonth)
+	}
+
+	return newLock(targetMonth, lockedAt, checksum, content)
+}
+
+func newLock(targetMonth string, lockedAt string, checksum string, content string) (*PeriodLock, error) {
+
+	lock := &PeriodLock{TargetMonth: targetMonth, Checksum: checksum}
+
+	t, err := time.Parse(storeTimeLayout, lockedAt)
+	if err != nil {
+		return nil, fmt.Errorf("time.Parse error: %v\nlockedAt=[%v]", err, lockedAt)
+	}
+	lock.LockedAt = t
+
+	if err := json.Unmarshal([]byte(content), &lock.Export); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error: %v\ntargetMonth=[%v]", err, targetMonth)
+	}
+
+	return lock, nil
+}
+
+func (s *Store) Locks() ([]PeriodLock, error) {
+
+	rows, err := s.db.Query("SELECT target_month, locked_at, checksum, content FROM locks ORDER BY target_month")
+	if err != nil {
+		return nil, fmt.Errorf("db.Query error: %v", err)
+	}
+	defer rows.Close()
+
+	locks := make([]PeriodLock, 0, 10)
+	for rows.Next() {
+		var targetMonth, lockedAt, checksum, content string
+		if err := rows.Scan(&targetMonth, &lockedAt, &checksum, &content); err != nil {
+			return nil, fmt.Errorf("rows.Scan error: %v", err)
+		}
+		lock, err := newLock(targetMonth, lockedAt, checksum, content)
+		if err != nil {
+			return nil, err
+		}
+		locks = append(locks, *lock)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err error: %v", err)
+	}
+
+	return locks, nil
+}
+
+func (s *Store) PutLock(lock *PeriodLock) error {
+
+	content, err := json.Marshal(lock.Export)
+	if err != nil {
+		return fmt.Errorf("json.Marshal error: %v\ntargetMonth=[%v]", err, lock.TargetMonth)
+	}
+
+	if _, err := s.db.Exec("INSERT INTO locks (target_month, locked_at, checksum, content) VALUES (?, ?, ?, ?)",
+		lock.TargetMonth, lock.LockedAt.Format(storeTimeLayout), lock.Checksum, string(content)); err != nil {
+		return fmt.Errorf("db.Exec error: %v\ntargetMonth=[%v]", err, lock.TargetMonth)
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteLock(targetMonth string) error {
+
+	result, err := s.db.Exec("DELETE FROM locks WHERE target_month = ?", targetMonth)
+	if err != nil {
+		return fmt.Errorf("db.Exec error: %v\ntargetMonth=[%v]", err, targetMonth)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("not locked: %v", targetMonth)
+	}
+
+	return nil
+}
+
+func checkLock(lock PeriodLock, issues IssueSearchResults, worklogs WorklogResults) *LockCheck {
+
+	current := lockExport(issues, worklogs)
+	return &LockCheck{
+		Lock:     lock,
+		Checksum: lockChecksum(current.Worklogs),
+		Result:   DiffExports(lock.Export, current),
+	}
+}
+
+func unfetchedIssues(stored Issues, issues IssueSearchResults) Issues {
+
+	fetched := map[string]bool{}
+	for _, result := range issues {
+		for _, issue := range result.Issues {
+			fetched[issue.siteKey()] = true
+		}
+	}
+
+	unfetched := make(Issues, 0, 10)
+	for _, issue := range stored {
+		if !fetched[issue.siteKey()] {
+			unfetched = append(unfetched, issue)
+		}
+	}
+
+	return unfetched
+}
+
+func issueWorklogs(issues Issues) (WorklogResults, []error) {
+
+	worklogs := make(WorklogResults, 0, 10)
+	searchErrors := make([]error, 0, 10)
+	for _, issue := range issues {
+		site, err := config.site(issue.Site)
+		if err != nil {
+			searchErrors = append(searchErrors, fmt.Errorf("config.site error: %v\nkey=[%v]", err, issue.Key))
+			continue
+		}
+
+		result, err := worklog(site, issue.Key)
+		if err == errEmptyWorklog {
+			continue
+		}
+		if err != nil {
+			searchErrors = append(searchErrors, fmt.Errorf("worklog error: %v\nsite=[%v],key=[%v]", err, issue.Site, issue.Key))
+			continue
+		}
+		worklogs = append(worklogs, *result)
+	}
+
+	return worklogs, searchErrors
+}
+
+func lockedIssueWorklogs(lock PeriodLock, issues IssueSearchResults) (WorklogResults, []error) {
+
+	return issueWorklogs(unfetchedIssues(lock.Export.Issues, issues))
+}
+
+func RenderLockCsv(w io.Writer, checks []LockCheck) error {
+
+	records := make([][]string, 0, 10)
+	records = append(records, []string{"対象年月", "ロック日時", "ロック時のチェックサム", "現在のチェックサム", "変更数"})
+	for _, check := range checks {
+		records = append(records, []string{
+			check.Lock.TargetMonth,
+			check.Lock.LockedAt.Format(storeTimeLayout),
+			check.Lock.Checksum,
+			check.Checksum,
+			strconv.Itoa(len(check.Result.Worklogs)),
+		})
+	}
+
+	records = append(records, []string{"対象年月", "変更", "キー", "作業ログID", "開始日時", "更新日時", "更新者", "変更前", "変更後", "差分"})
+	for _, check := range checks {
+		for _, change := range check.Result.Worklogs {
+			var before, after TimeTotal
+			if change.Before != nil {
+				before.Add(change.Before.Timespentseconds)
+			}
+			if change.After != nil {
+				after.Add(change.After.Timespentseconds)
+			}
+			worklog := change.worklog()
+			updateAuthor := worklog.Author
+			if worklog.UpdateAuthor != nil {
+				updateAuthor = *worklog.UpdateAuthor
+			}
+			record := []string{check.Lock.TargetMonth, change.Change, worklog.siteKey(), worklog.Id, worklog.Started, worklog.Updated, updateAuthor.Displayname}
+			records = append(records, append(record, timeDeltaRecord(before, after)...))
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("writer.Write error: %v\nrecord=[%v]\n", err, record)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("writer.Error error: %v\n", err)
+	}
+
+	return nil
+}
+
+func lateChangeErrors(checks []LockCheck) []error {
+
+	lateChangeErrors := make([]error, 0, len(checks))
+	for _, check := range checks {
+		if check.IsChanged() {
+			lateChangeErrors = append(lateChangeErrors, &LateChangeError{TargetMonth: check.Lock.TargetMonth, Changes: len(check.Result.Worklogs)})
+		}
+	}
+
+	return lateChangeErrors
+}
+
+func Lock(w io.Writer) []error {
+
+	targetMonth, err := config.targetMonthText()
+	if err != nil {
+		return []error{fmt.Errorf("config.targetMonthText error: %v", err)}
+	}
+
+	store, err := OpenStore(config.Database)
+	if err != nil {
+		return []error{fmt.Errorf("OpenStore error: %v", err)}
+	}
+	defer store.Close()
+
+	locked, err := store.lock(targetMonth)
+	if err != nil {
+		return []error{err}
+	}
+	if locked != nil {
+		return []error{fmt.Errorf("already locked: %v", targetMonth)}
+	}
+
+	issues, worklogs, lockErrors := Search()
+	if len(lockErrors) > 0 {
+		return lockErrors
+	}
+
+	export := lockExport(issues, worklogs)
+	lock := &PeriodLock{TargetMonth: targetMonth, LockedAt: config.clock(), Checksum: lockChecksum(export.Worklogs), Export: export}
+	if err := store.PutLock(lock); err != nil {
+		return append(lockErrors, fmt.Errorf("store.PutLock error: %v", err))
+	}
+
+	if err := RenderLockCsv(w, []LockCheck{{Lock: *lock, Checksum: lock.Checksum, Result: &DiffResult{}}}); err != nil {
+		lockErrors = append(lockErrors, err)
+	}
+
+	return lockErrors
+}
+
+func Unlock(w io.Writer) []error {
+
+	targetMonth, err := config.targetMonthText()
+	if err != nil {
+		return []error{fmt.Errorf("config.targetMonthText error: %v", err)}
+	}
+
+	store, err := OpenStore(config.Database)
+	if err != nil {
+		return []error{fmt.Errorf("OpenStore error: %v", err)}
+	}
+	defer store.Close()
+
+	if err := store.DeleteLock(targetMonth); err != nil {
+		return []error{fmt.Errorf("store.DeleteLock error: %v", err)}
+	}
+
+	log.Printf("unlock: targetMonth=[%v]\n", targetMonth)
+	return nil
+}
+
+func Check(w io.Writer) []error {
+
+	store, err := OpenStore(config.Database)
+	if err != nil {
+		return []error{fmt.Errorf("OpenStore error: %v", err)}
+	}
+	defer store.Close()
+
+	locks, err := store.Locks()
+	if err != nil {
+		return []error{fmt.Errorf("store.Locks error: %v", err)}
+	}
+
+	checkErrors := make([]error, 0, 10)
+	checks := make([]LockCheck, 0, len(locks))
+	targetYearMonth := config.TargetYearMonth
+	defer func() { config.TargetYearMonth = targetYearMonth }()
+	for _, lock := range locks {
+		config.TargetYearMonth = lock.TargetMonth
+
+		issues, worklogs, searchErrors := Search()
+		checkErrors = append(checkErrors, searchErrors...)
+		if !config.FromDb {
+			lockedWorklogs, lockedErrors := lockedIssueWorklogs(lock, issues)
+			worklogs = append(worklogs, lockedWorklogs...)
+			checkErrors = append(checkErrors, lockedErrors...)
+		}
+
+		checks = append(checks, *checkLock(lock, issues, worklogs))
+	}
+
+	if err := RenderLockCsv(w, checks); err != nil {
+		checkErrors = append(checkErrors, err)
+	}
+
+	return append(checkErrors, lateChangeErrors(checks)...)
+}
@@ -0,0 +1,118 @@
+package jira
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckLock(t *testing.T) {
+	saved := *config
+	defer func() { *config = saved }()
+	config.TargetYearMonth = "2020-08"
+	config.TimeUnit = "hh"
+	config.Sites = ""
+
+	alice := User{AccountId: "5b10a2844c20165700ede21g", Displayname: "Alice"}
+	bob := User{AccountId: "5b10ac8d82e05b22cc7d4ef5", Displayname: "Bob"}
+	issues := IssueSearchResults{{Issues: Issues{{Key: "DEMO-1"}, {Key: "DEMO-2"}}}}
+	locked := WorklogResults{{Worklogs: Worklogs{
+		{Key: "DEMO-1", Id: "30001", Author: alice, Started: "2020-08-03T09:00:00.000+0900", Timespentseconds: 28800},
+		{Key: "DEMO-1", Id: "30002", Author: bob, Started: "2020-08-04T13:00:00.000+0900", Timespentseconds: 14400},
+	}}}
+	export := lockExport(issues, locked)
+	lock := PeriodLock{TargetMonth: "2020-08", Checksum: lockChecksum(export.Worklogs), Export: export}
+
+	tests := []struct {
+		name     string
+		worklogs WorklogResults
+		changes  []string
+	}{
+		{
+			name:     "unchanged",
+			worklogs: locked,
+		},
+		{
+			name: "later month",
+			worklogs: WorklogResults{{Worklogs: append(Worklogs{
+				{Key: "DEMO-2", Id: "30003", Author: bob, Started: "2020-09-01T09:00:00.000+0900", Timespentseconds: 3600},
+			}, locked[0].Worklogs...)}},
+		},
+		{
+			name: "created, updated and deleted",
+			worklogs: WorklogResults{{Worklogs: Worklogs{
+				{Key: "DEMO-1", Id: "30001", Author: alice, Started: "2020-08-03T09:00:00.000+0900", Timespentseconds: 21600,
+					Updated: "2020-09-10T10:00:00.000+0900", UpdateAuthor: &bob},
+				{Key: "DEMO-2", Id: "30003", Author: bob, Started: "2020-08-31T09:00:00.000+0900", Timespentseconds: 3600,
+					Updated: "2020-09-11T10:00:00.000+0900"},
+			}}},
+			changes: []string{
+				"2020-08,変更,DEMO-1,30001,2020-08-03T09:00:00.000+0900,2020-09-10T10:00:00.000+0900,Bob,8.00,6.00,-2.00",
+				"2020-08,削除,DEMO-1,30002,2020-08-04T13:00:00.000+0900,,Bob,4.00,0.00,-4.00",
+				"2020-08,追加,DEMO-2,30003,2020-08-31T09:00:00.000+0900,2020-09-11T10:00:00.000+0900,Bob,0.00,1.00,1.00",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := checkLock(lock, issues, tt.worklogs)
+			if check.IsChanged() != (len(tt.changes) > 0) {
+				t.Errorf("expected=[%v] <> actual[%v]\n", len(tt.changes) > 0, check.IsChanged())
+			}
+
+			var buf bytes.Buffer
+			if err := RenderLockCsv(&buf, []LockCheck{*check}); err != nil {
+				t.Fatalf("RenderLockCsv() error = %v", err)
+			}
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if actual := lines[3:]; strings.Join(actual, "\n") != strings.Join(tt.changes, "\n") {
+				t.Errorf("expected=[%v] <> actual[%v]\n", tt.changes, actual)
+			}
+		})
+	}
+}
+
+func TestLockAndCheck(t *testing.T) {
+	server := setupFakeJira(t)
+	config.Database = filepath.Join(t.TempDir(), "store.db")
+
+	config.Command = commandLock
+	server.FailNext(100)
+	if lockErrors := Lock(&bytes.Buffer{}); len(lockErrors) == 0 {
+		t.Fatalf("Lock() errors = nil, want search errors")
+	}
+	server.FailNext(0)
+	cache.clear()
+	if lockErrors := Lock(&bytes.Buffer{}); len(lockErrors) > 0 {
+		t.Fatalf("Lock() errors = %v", lockErrors)
+	}
+	if lockErrors := Lock(&bytes.Buffer{}); len(lockErrors) == 0 {
+		t.Errorf("Lock() errors = nil, want already locked")
+	}
+
+	config.Command = commandCheck
+	config.TargetYearMonth = "2020-09"
+	var buf bytes.Buffer
+	if checkErrors := Check(&buf); len(checkErrors) > 0 {
+		t.Fatalf("Check() errors = %v", checkErrors)
+	}
+	if !strings.Contains(buf.String(), "\n2020-08,") || config.TargetYearMonth != "2020-09" {
+		t.Errorf("expected=[2020-08] <> actual[%v]\n", buf.String())
+	}
+
+	config.Query = "project = OPS"
+	checkErrors := Check(&buf)
+	if len(checkErrors) != 1 || !IsLateChange(checkErrors[0]) {
+		t.Errorf("expected=[late change] <> actual[%v]\n", checkErrors)
+	}
+
+	config.Command = commandUnlock
+	config.TargetYearMonth = "2020-08"
+	if unlockErrors := Unlock(&buf); len(unlockErrors) > 0 {
+		t.Fatalf("Unlock() errors = %v", unlockErrors)
+	}
+	if unlockErrors := Unlock(&buf); len(unlockErrors) == 0 {
+		t.Errorf("Unlock() errors = nil, want not locked")
+	}
+}
@@ -0,0 +1,261 @@
+package jira
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	defaultMailSubject = "jira-timespent-report {{.Name}} {{.TargetMonth}}"
+	base64LineLength   = 76
+)
+
+var mailBodyTemplate = htmltemplate.Must(htmltemplate.New("mail").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head><meta charset="utf-8"></head>
+<body>
+<p>{{.Name}} ({{.TargetMonth}})</p>
+<table border="1">
+<tr><th>課題数</th><th>作業ログ数</th><th>消費時間</th></tr>
+<tr><td>{{.Issues}}</td><td>{{.Worklogs}}</td><td>{{.Total}}</td></tr>
+</table>
+{{if .Authors}}<table border="1">
+<tr><th>表示名</th><th>メールアドレス</th><th>消費時間</th></tr>
+{{range .Authors}}<tr><td>{{.Displayname}}</td><td>{{.Emailaddress}}</td><td>{{.Timespent}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+type Mail struct {
+	To          []string
+	Subject     string
+	Summary     *ReportSummary
+	FileName    string
+	ContentType string
+	Body        []byte
+}
+
+func mailSubject(text string, summary *ReportSummary) (string, error) {
+
+	if len(text) == 0 {
+		text = defaultMailSubject
+	}
+
+	t, err := template.New("subject").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("template.Parse error: %v\nsubject=[%v]", err, text)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, summary); err != nil {
+		return "", fmt.Errorf("template.Execute error: %v\nsubject=[%v]", err, text)
+	}
+
+	return buf.String(), nil
+}
+
+func mailRecipients(to string) []string {
+
+	recipients := make([]string, 0, 2)
+	for _, recipient := range strings.Split(to, ",") {
+		if recipient = strings.TrimSpace(recipient); len(recipient) > 0 {
+			recipients = append(recipients, recipient)
+		}
+	}
+
+	return recipients
+}
+
+func writeBase64(w io.Writer, body []byte) error {
+
+	encoded := base64.StdEncoding.EncodeToString(body)
+	for len(encoded) > 0 {
+		n := base64LineLength
+		if len(encoded) < n {
+			n = len(encoded)
+		}
+		if _, err := fmt.Fprintf(w, "%s\r\n", encoded[:n]); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+
+	return nil
+}
+
+func (m *Mail) message(from string, date time.Time) ([]byte, error) {
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.BEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+
+	var html bytes.Buffer
+	if err := mailBodyTemplate.Execute(&html, m.Summary); err != nil {
+		return nil, fmt.Errorf("mailBodyTemplate.Execute error: %v", err)
+	}
+
+	parts := []struct {
+		header textproto.MIMEHeader
+		body   []byte
+	}{
+		{
+			header: textproto.MIMEHeader{
+				"Content-Type":              {"text/html; charset=utf-8"},
+				"Content-Transfer-Encoding": {"base64"},
+			},
+			body: html.Bytes(),
+		},
+		{
+			header: textproto.MIMEHeader{
+				"Content-Type":              {m.ContentType + "; charset=utf-8"},
+				"Content-Transfer-Encoding": {"base64"},
+				"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": m.FileName})},
+			},
+			body: m.Body,
+		},
+	}
+	for _, part := range parts {
+		w, err := writer.CreatePart(part.header)
+		if err != nil {
+			return nil, fmt.Errorf("writer.CreatePart error: %v", err)
+		}
+		if err := writeBase64(w, part.body); err != nil {
+			return nil, fmt.Errorf("writeBase64 error: %v", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("writer.Close error: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (c *Config) sendMail(m *Mail) error {
+
+	if len(c.SmtpHost) == 0 || len(c.SmtpFrom) == 0 {
+		return fmt.Errorf("empty smtp host or from address")
+	}
+	if len(m.To) == 0 {
+		return fmt.Errorf("empty mail recipients")
+	}
+
+	message, err := m.message(c.SmtpFrom, c.clock())
+	if err != nil {
+		return err
+	}
+
+	host := c.SmtpHost
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+
+	client, err := smtp.Dial(c.SmtpHost)
+	if err != nil {
+		return fmt.Errorf("smtp.Dial error: %v\nSmtpHost=[%v]", err, c.SmtpHost)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("client.StartTLS error: %v\nSmtpHost=[%v]", err, c.SmtpHost)
+		}
+	} else if c.SmtpStartTLS {
+		return fmt.Errorf("smtp server does not support STARTTLS\nSmtpHost=[%v]", c.SmtpHost)
+	}
+
+	if user := os.Getenv("SMTP_USER"); len(user) > 0 {
+		if err := client.Auth(smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)); err != nil {
+			return fmt.Errorf("client.Auth error: %v\nSmtpHost=[%v],user=[%v]", err, c.SmtpHost, user)
+		}
+	}
+
+	if err := client.Mail(c.SmtpFrom); err != nil {
+		return fmt.Errorf("client.Mail error: %v\nSmtpFrom=[%v]", err, c.SmtpFrom)
+	}
+	for _, to := range m.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("client.Rcpt error: %v\nto=[%v]", err, to)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("client.Data error: %v", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("w.Write error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("w.Close error: %v", err)
+	}
+
+	return client.Quit()
+}
+
+func reportFormat() string {
+
+	if config.Report == reportJson {
+		return formatJson
+	}
+
+	return formatCsv
+}
+
+func IsJsonReport() bool {
+
+	return reportFormat() == formatJson
+}
+
+func contentType(format string) string {
+
+	if format == formatJson {
+		return "application/json"
+	}
+
+	return "text/csv"
+}
+
+func MailReport(body []byte, issues IssueSearchResults, worklogs WorklogResults) error {
+
+	report := reportName()
+	summary := newReportSummary(report, issues, worklogs)
+	subject, err := mailSubject(config.MailSubject, summary)
+	if err != nil {
+		return err
+	}
+
+	format := reportFormat()
+	return config.sendMail(&Mail{
+		To:          mailRecipients(config.MailTo),
+		Subject:     subject,
+		Summary:     summary,
+		FileName:    fmt.Sprintf("%s-%s.%s", report, summary.TargetMonth, format),
+		ContentType: contentType(format),
+		Body:        body,
+	})
+}
+
+func IsMail() bool {
+
+	return len(config.MailTo) > 0
+}
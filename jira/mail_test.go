@@ -0,0 +1,166 @@
+package jira
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"strings"
+	"testing"
+
+	"bitbucket.org/yujiorama/jira-timespent-report/jira/jiratest"
+)
+
+func setupSMTPServer(t *testing.T) *jiratest.SMTPServer {
+	server, err := jiratest.NewSMTPServer()
+	if err != nil {
+		t.Fatalf("jiratest.NewSMTPServer() error = %v", err)
+	}
+	server.User, server.Password = "report", "secret"
+
+	user, password := os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASSWORD")
+	t.Cleanup(func() {
+		server.Close()
+		os.Setenv("SMTP_USER", user)
+		os.Setenv("SMTP_PASSWORD", password)
+	})
+	os.Setenv("SMTP_USER", server.User)
+	os.Setenv("SMTP_PASSWORD", server.Password)
+
+	config.SmtpHost = server.Addr
+	config.SmtpFrom = "report@example.com"
+
+	return server
+}
+
+func readBase64Part(t *testing.T, part *multipart.Part) []byte {
+	body, err := ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll() error = %v", err)
+	}
+
+	return body
+}
+
+func TestMailSubject(t *testing.T) {
+	summary := &ReportSummary{Name: "timesheet", TargetMonth: "2020-08"}
+
+	tests := []struct {
+		subject  string
+		expected string
+		wantErr  bool
+	}{
+		{subject: "", expected: "jira-timespent-report timesheet 2020-08"},
+		{subject: "{{.TargetMonth}} 作業時間", expected: "2020-08 作業時間"},
+		{subject: "{{.TargetMonth", wantErr: true},
+		{subject: "{{.Unknown}}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.subject, func(t *testing.T) {
+			actual, err := mailSubject(tt.subject, summary)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("mailSubject() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if actual != tt.expected {
+				t.Errorf("expected=[%v] <> actual[%v]\n", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMailReport(t *testing.T) {
+	setupFakeJira(t)
+	server := setupSMTPServer(t)
+	config.Worklog = true
+	config.Report = "timesheet"
+	config.MailTo = "finance@example.com, manager@example.com"
+	config.MailSubject = "{{.TargetMonth}} 作業時間"
+
+	issues, worklogs, searchErrors := Search()
+	if len(searchErrors) > 0 {
+		t.Fatalf("Search() errors = %v", searchErrors)
+	}
+	var buf bytes.Buffer
+	if reportErrors := Report(&buf, issues, worklogs); len(reportErrors) > 0 {
+		t.Fatalf("Report() errors = %v", reportErrors)
+	}
+	if err := MailReport(buf.Bytes(), issues, worklogs); err != nil {
+		t.Fatalf("MailReport() error = %v", err)
+	}
+
+	mails := server.Mails()
+	if len(mails) != 1 {
+		t.Fatalf("expected=[1] <> actual[%v]\n", len(mails))
+	}
+	if actual := strings.Join(mails[0].To, ","); actual != "finance@example.com,manager@example.com" {
+		t.Errorf("expected=[finance@example.com,manager@example.com] <> actual[%v]\n", actual)
+	}
+
+	message, err := mail.ReadMessage(strings.NewReader(mails[0].Data))
+	if err != nil {
+		t.Fatalf("mail.ReadMessage() error = %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	if err != nil || subject != "2020-08 作業時間" {
+		t.Errorf("expected=[2020-08 作業時間] <> actual[%v]\n", subject)
+	}
+
+	_, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("mime.ParseMediaType() error = %v", err)
+	}
+	reader := multipart.NewReader(message.Body, params["boundary"])
+
+	html, err := reader.NextPart()
+	if err != nil {
+		t.Fatalf("reader.NextPart() error = %v", err)
+	}
+	body := readBase64Part(t, html)
+	if !strings.Contains(string(body), "<th>作業ログ数</th>") || !strings.Contains(string(body), "alice@example.com") {
+		t.Errorf("expected=[summary table] <> actual[%v]\n", string(body))
+	}
+
+	attachment, err := reader.NextPart()
+	if err != nil {
+		t.Fatalf("reader.NextPart() error = %v", err)
+	}
+	attached := readBase64Part(t, attachment)
+	if attachment.FileName() != "timesheet-2020-08.csv" || string(attached) != buf.String() {
+		t.Errorf("expected=[timesheet-2020-08.csv] <> actual[%v]\n", attachment.FileName())
+	}
+}
+
+func TestMailReport_Error(t *testing.T) {
+	setupFakeJira(t)
+	server := setupSMTPServer(t)
+	config.MailTo = "finance@example.com"
+
+	tests := []struct {
+		name  string
+		setup func()
+	}{
+		{name: "starttls", setup: func() { config.SmtpStartTLS = true }},
+		{name: "auth", setup: func() { os.Setenv("SMTP_PASSWORD", "wrong") }},
+		{name: "recipients", setup: func() { config.MailTo = " , " }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			defer func() {
+				config.SmtpStartTLS = false
+				config.MailTo = "finance@example.com"
+				os.Setenv("SMTP_PASSWORD", server.Password)
+			}()
+
+			if err := MailReport([]byte("キー\n"), IssueSearchResults{}, nil); err == nil {
+				t.Errorf("expected=[error] <> actual[%v]\n", err)
+			}
+		})
+	}
+	if mails := server.Mails(); len(mails) != 0 {
+		t.Errorf("expected=[0] <> actual[%v]\n", len(mails))
+	}
+}
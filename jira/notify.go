@@ -0,0 +1,179 @@
+package jira
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+)
+
+const defaultNotifyTemplate = `{{.Name}} {{.TargetMonth}}
+合計 {{.TotalHours}} 時間 (課題 {{.Issues}} 件、作業ログ {{.Worklogs}} 件)
+{{if .TopIssues}}消費時間の多い課題:
+{{range .TopIssues}}- {{.Key}} {{.Summary}} {{.Timespent}}
+{{end}}{{end}}{{if .MissingAuthors}}作業ログが無い日がある人:
+{{range .MissingAuthors}}- {{.Displayname}} {{.Days}} 日
+{{end}}{{end}}{{if .Link}}{{.Link}}
+{{end}}`
+
+type Notifier interface {
+	Notify(summary *ReportSummary) error
+}
+
+type NotifyTarget struct {
+	Slack    string `yaml:"slack"`
+	Teams    string `yaml:"teams"`
+	Template string `yaml:"template"`
+}
+
+type SlackNotifier struct {
+	URL      string
+	Template *template.Template
+}
+
+type TeamsNotifier struct {
+	URL      string
+	Template *template.Template
+}
+
+func notifyTemplate(text string) (*template.Template, error) {
+
+	if len(text) == 0 {
+		text = defaultNotifyTemplate
+	}
+
+	t, err := template.New("notify").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("template.Parse error: %v\ntemplate=[%v]", err, text)
+	}
+
+	return t, nil
+}
+
+func notifyText(t *template.Template, summary *ReportSummary) (string, error) {
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, summary); err != nil {
+		return "", fmt.Errorf("template.Execute error: %v", err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+func postJson(url string, v interface{}) error {
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("json.Marshal error: %v", err)
+	}
+
+	return deliverWebhook(url, "application/json", body)
+}
+
+func (n *SlackNotifier) Notify(summary *ReportSummary) error {
+
+	text, err := notifyText(n.Template, summary)
+	if err != nil {
+		return err
+	}
+
+	return postJson(n.URL, map[string]string{"text": text})
+}
+
+func (n *TeamsNotifier) Notify(summary *ReportSummary) error {
+
+	text, err := notifyText(n.Template, summary)
+	if err != nil {
+		return err
+	}
+
+	return postJson(n.URL, map[string]string{
+		"@type":    "MessageCard",
+		"@context": "https://schema.org/extensions",
+		"summary":  summary.Name,
+		"text":     strings.ReplaceAll(text, "\n", "\n\n"),
+	})
+}
+
+func (t *NotifyTarget) validate() error {
+
+	if (len(t.Slack) > 0) == (len(t.Teams) > 0) {
+		return fmt.Errorf("notify must have one of slack or teams")
+	}
+
+	_, err := notifyTemplate(t.Template)
+	return err
+}
+
+func (t *NotifyTarget) Notifier() (Notifier, error) {
+
+	tmpl, err := notifyTemplate(t.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(t.Slack) > 0 {
+		return &SlackNotifier{URL: t.Slack, Template: tmpl}, nil
+	}
+
+	return &TeamsNotifier{URL: t.Teams, Template: tmpl}, nil
+}
+
+func (c *Config) notifyTargets() ([]NotifyTarget, error) {
+
+	text := ""
+	if len(c.NotifyTemplate) > 0 {
+		body, err := ioutil.ReadFile(c.NotifyTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("ioutil.ReadFile error: %v\nNotifyTemplate=[%v]", err, c.NotifyTemplate)
+		}
+		text = string(body)
+	}
+
+	targets := make([]NotifyTarget, 0, 2)
+	if len(c.SlackWebhook) > 0 {
+		targets = append(targets, NotifyTarget{Slack: c.SlackWebhook, Template: text})
+	}
+	if len(c.TeamsWebhook) > 0 {
+		targets = append(targets, NotifyTarget{Teams: c.TeamsWebhook, Template: text})
+	}
+
+	return targets, nil
+}
+
+func notify(targets []NotifyTarget, summary *ReportSummary) []error {
+
+	notifyErrors := make([]error, 0, len(targets))
+	for _, target := range targets {
+		notifier, err := target.Notifier()
+		if err != nil {
+			notifyErrors = append(notifyErrors, err)
+			continue
+		}
+		if err := notifier.Notify(summary); err != nil {
+			notifyErrors = append(notifyErrors, fmt.Errorf("Notify error: %v", err))
+		}
+	}
+
+	return notifyErrors
+}
+
+func IsNotify() bool {
+
+	return len(config.SlackWebhook) > 0 || len(config.TeamsWebhook) > 0
+}
+
+func NotifyReport(issues IssueSearchResults, worklogs WorklogResults) []error {
+
+	targets, err := config.notifyTargets()
+	if err != nil {
+		return []error{err}
+	}
+
+	summary := newReportSummary(reportName(), issues, worklogs)
+	summary.Link = config.NotifyLink
+
+	return notify(targets, summary)
+}
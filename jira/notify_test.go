@@ -0,0 +1,115 @@
+package jira
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type webhookStandIn struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	payloads map[string]map[string]string
+}
+
+func setupWebhookStandIn(t *testing.T) *webhookStandIn {
+	s := &webhookStandIn{payloads: map[string]map[string]string{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var payload map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.payloads[r.URL.Path] = payload
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *webhookStandIn) payload(path string) map[string]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.payloads[path]
+}
+
+func TestNotifyReport(t *testing.T) {
+	setupFakeJira(t)
+	webhook := setupWebhookStandIn(t)
+	config.Worklog = true
+	config.SlackWebhook = webhook.URL + "/slack"
+	config.TeamsWebhook = webhook.URL + "/teams"
+	config.NotifyLink = "https://files.example.com/timespent-2020-08.csv"
+
+	issues, worklogs, searchErrors := Search()
+	if len(searchErrors) > 0 {
+		t.Fatalf("Search() errors = %v", searchErrors)
+	}
+	if notifyErrors := NotifyReport(issues, worklogs); len(notifyErrors) > 0 {
+		t.Fatalf("NotifyReport() errors = %v", notifyErrors)
+	}
+
+	text := webhook.payload("/slack")["text"]
+	for _, expected := range []string{
+		"timespent 2020-08\n合計 19.00 時間 (課題 3 件、作業ログ 4 件)\n",
+		"消費時間の多い課題:\n- DEMO-1 ログイン画面の作成 12.00\n- DEMO-2 ログイン画面の単体テスト 4.00\n",
+		"作業ログが無い日がある人:\n- Alice 18 日\n- Bob 18 日\n",
+		"https://files.example.com/timespent-2020-08.csv",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("expected=[%v] <> actual[%v]\n", expected, text)
+		}
+	}
+
+	teams := webhook.payload("/teams")
+	if teams["@type"] != "MessageCard" || !strings.Contains(teams["text"], "timespent 2020-08\n\n合計 19.00 時間") {
+		t.Errorf("expected=[MessageCard] <> actual[%v]\n", teams)
+	}
+}
+
+func TestNotifyReport_Template(t *testing.T) {
+	setupFakeJira(t)
+	webhook := setupWebhookStandIn(t)
+	config.SlackWebhook = webhook.URL + "/slack"
+
+	tests := []struct {
+		name     string
+		template string
+		webhook  string
+		expected string
+		wantErr  bool
+	}{
+		{name: "custom", template: "{{.TargetMonth}} {{.Issues}} issues", webhook: "/slack", expected: "2020-08 3 issues"},
+		{name: "invalid template", template: "{{.TargetMonth", webhook: "/slack", wantErr: true},
+		{name: "unknown field", template: "{{.Unknown}}", webhook: "/slack", wantErr: true},
+		{name: "error status", template: "{{.TargetMonth}}", webhook: "/error", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.NotifyTemplate = filepath.Join(t.TempDir(), "notify.tmpl")
+			if err := ioutil.WriteFile(config.NotifyTemplate, []byte(tt.template), 0644); err != nil {
+				t.Fatalf("ioutil.WriteFile() error = %v", err)
+			}
+			config.SlackWebhook = webhook.URL + tt.webhook
+
+			notifyErrors := NotifyReport(IssueSearchResults{{Issues: Issues{{Key: "DEMO-1"}, {Key: "DEMO-2"}, {Key: "DEMO-3"}}}}, nil)
+			if (len(notifyErrors) > 0) != tt.wantErr {
+				t.Fatalf("NotifyReport() errors = %v, wantErr %v", notifyErrors, tt.wantErr)
+			}
+			if actual := webhook.payload("/slack")["text"]; !tt.wantErr && actual != tt.expected {
+				t.Errorf("expected=[%v] <> actual[%v]\n", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package jira
+
+import (
+	"strings"
+	"sync"
+)
+
+const (
+	ProgressPage    = "page"
+	ProgressIssues  = "issues"
+	ProgressWorklog = "worklog"
+	ProgressError   = "error"
+	ProgressRetry   = "retry"
+	ProgressDone    = "done"
+)
+
+type ProgressEvent struct {
+	Kind            string `json:"kind"`
+	Site            string `json:"site,omitempty"`
+	Key             string `json:"key,omitempty"`
+	Message         string `json:"message,omitempty"`
+	Pages           int    `json:"pages"`
+	IssuesFound     int    `json:"issuesFound"`
+	Issues          int    `json:"issues"`
+	IssuesProcessed int    `json:"issuesProcessed"`
+	Worklogs        int    `json:"worklogs"`
+	Errors          int    `json:"errors"`
+	Retries         int    `json:"retries"`
+}
+
+type ProgressFunc func(event ProgressEvent)
+
+type progress struct {
+	mutex  sync.Mutex
+	counts ProgressEvent
+	f      ProgressFunc
+}
+
+func SetProgress(f ProgressFunc) {
+
+	if f == nil {
+		config.progress = nil
+		return
+	}
+
+	config.progress = &progress{f: f}
+}
+
+func (p *progress) emit(kind string, site string, key string, message string, update func(counts *ProgressEvent)) {
+
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	update(&p.counts)
+	event := p.counts
+	event.Kind = kind
+	event.Site = site
+	event.Key = key
+	event.Message = message
+	p.f(event)
+}
+
+func (p *progress) page(site string, issues int) {
+
+	p.emit(ProgressPage, site, "", "", func(counts *ProgressEvent) {
+		counts.Pages++
+		counts.IssuesFound += issues
+	})
+}
+
+func (p *progress) issues(results IssueSearchResults) {
+
+	n := 0
+	for _, result := range results {
+		n += len(result.Issues)
+	}
+	p.emit(ProgressIssues, "", "", "", func(counts *ProgressEvent) {
+		counts.Issues = n
+	})
+}
+
+func (p *progress) worklog(site string, key string, worklogs int) {
+
+	p.emit(ProgressWorklog, site, key, "", func(counts *ProgressEvent) {
+		counts.IssuesProcessed++
+		counts.Worklogs += worklogs
+	})
+}
+
+func (p *progress) errors(errors []error) {
+
+	for _, err := range errors {
+		message := err.Error()
+		if i := strings.Index(message, "\n"); i >= 0 {
+			message = message[:i]
+		}
+		p.emit(ProgressError, "", "", message, func(counts *ProgressEvent) {
+			counts.Errors++
+		})
+	}
+}
+
+func (p *progress) retry(site string, message string) {
+
+	p.emit(ProgressRetry, site, "", message, func(counts *ProgressEvent) {
+		counts.Retries++
+	})
+}
+
+func (p *progress) done() {
+
+	p.emit(ProgressDone, "", "", "", func(counts *ProgressEvent) {})
+}
@@ -0,0 +1,59 @@
+package jira
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSearch_Progress(t *testing.T) {
+	tests := []struct {
+		name     string
+		fail     int
+		maxRetry int
+		expected ProgressEvent
+	}{
+		{
+			name:     "pages and worklogs",
+			expected: ProgressEvent{Kind: ProgressDone, Pages: 2, IssuesFound: 3, Issues: 3, IssuesProcessed: 3, Worklogs: 4},
+		},
+		{
+			name:     "errors",
+			fail:     1,
+			expected: ProgressEvent{Kind: ProgressDone, Errors: 1},
+		},
+		{
+			name:     "retries",
+			fail:     1,
+			maxRetry: 1,
+			expected: ProgressEvent{Kind: ProgressDone, Pages: 2, IssuesFound: 3, Issues: 3, IssuesProcessed: 3, Worklogs: 4, Retries: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := setupFakeJira(t)
+			server.FailNext(tt.fail)
+			config.Worklog = true
+			config.MaxRetry = tt.maxRetry
+
+			var mutex sync.Mutex
+			kinds := map[string]int{}
+			var last ProgressEvent
+			SetProgress(func(event ProgressEvent) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				kinds[event.Kind]++
+				last = event
+			})
+
+			Search()
+
+			last.Site, last.Key, last.Message = "", "", ""
+			if last != tt.expected {
+				t.Errorf("expected=[%v] <> actual[%v]\n", tt.expected, last)
+			}
+			if kinds[ProgressPage] != tt.expected.Pages || kinds[ProgressWorklog] != tt.expected.IssuesProcessed || kinds[ProgressDone] != 1 {
+				t.Errorf("expected=[%v] <> actual[%v]\n", tt.expected, kinds)
+			}
+		})
+	}
+}
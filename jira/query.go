@@ -0,0 +1,208 @@
+package jira
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+type NamedQuery struct {
+	Name   string
+	Query  string
+	Filter string
+}
+
+type NamedQueries []NamedQuery
+
+type namedQueryFlag struct {
+	queries *NamedQueries
+	filter  bool
+}
+
+func (f *namedQueryFlag) String() string {
+
+	if f.queries == nil {
+		return ""
+	}
+
+	values := make([]string, 0, len(*f.queries))
+	for _, q := range *f.queries {
+		if f.filter == (len(q.Filter) > 0) {
+			values = append(values, q.String())
+		}
+	}
+
+	return strings.Join(values, " ")
+}
+
+func (f *namedQueryFlag) Set(value string) error {
+
+	q, err := parseNamedQuery(value, f.filter)
+	if err != nil {
+		return err
+	}
+
+	*f.queries = append(*f.queries, q)
+	return nil
+}
+
+func parseNamedQuery(value string, filter bool) (NamedQuery, error) {
+
+	i := strings.Index(value, "=")
+	if i <= 0 || i == len(value)-1 || strings.ContainsAny(value[:i], " \t") {
+		return NamedQuery{}, fmt.Errorf("invalid named query: %v (name=value)", value)
+	}
+
+	q := NamedQuery{Name: value[:i]}
+	if filter {
+		q.Filter = strings.TrimSpace(value[i+1:])
+	} else {
+		q.Query = strings.TrimSpace(value[i+1:])
+	}
+
+	return q, nil
+}
+
+func (q NamedQuery) String() string {
+
+	if len(q.Filter) > 0 {
+		return fmt.Sprintf("%s=%s", q.Name, q.Filter)
+	}
+
+	return fmt.Sprintf("%s=%s", q.Name, q.Query)
+}
+
+func (c *Config) namedQueries() NamedQueries {
+
+	if len(c.Queries) > 0 {
+		return c.Queries
+	}
+
+	return NamedQueries{{Query: c.Query, Filter: c.Filter}}
+}
+
+func (c *Config) hasNamedQueries() bool {
+
+	return len(c.Queries) > 0
+}
+
+func (q NamedQuery) jql(site Site) (string, error) {
+
+	jql := q.Query
+	if len(q.Filter) > 0 {
+		filterQuery, err := getFilterJql(site, q.Filter)
+		if err != nil {
+			return "", fmt.Errorf("getFilterJql error: %v\nfilter=[%v]", err, q.Filter)
+		}
+		if len(q.Query) > 0 && q.Query != filterQuery {
+			log.Printf("filter overrides query: name=[%v],filter=[%v],query=[%v]\n", q.Name, q.Filter, q.Query)
+		}
+		jql = filterQuery
+	}
+
+	jql = appendJqlCondition(jql, config.builderJql())
+
+	if len(config.TargetYearMonth) > 0 {
+		if dateCondition, ok := config.dateCondition(); ok {
+			jql = composeJql(jql, dateCondition)
+		}
+	}
+
+	return jql, nil
+}
+
+func IssueSearch(maxResult int) (IssueSearchResults, []error) {
+
+	sites, err := config.sites()
+	if err != nil {
+		return IssueSearchResults{}, []error{fmt.Errorf("config.sites error: %v", err)}
+	}
+
+	type search struct {
+		site  Site
+		query NamedQuery
+	}
+	searches := make([]search, 0, len(sites))
+	for _, site := range sites {
+		for _, q := range config.namedQueries() {
+			searches = append(searches, search{site: site, query: q})
+		}
+	}
+
+	queries := make(NamedQueries, len(searches))
+	queryResults := make([]IssueSearchResults, len(searches))
+	queryErrors := make([][]error, len(searches))
+
+	workerSize := len(searches)
+	if workerSize > maxWorkerSize {
+		workerSize = maxWorkerSize
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workerSize)
+	searchIndexCh := make(chan int, len(searches))
+	for n := 0; n < workerSize; n++ {
+		go func() {
+			defer wg.Done()
+			for i := range searchIndexCh {
+				queryResults[i], queryErrors[i] = issueSearch(searches[i].site, searches[i].query, maxResult)
+			}
+		}()
+	}
+
+	for i, s := range searches {
+		queries[i] = s.query
+		searchIndexCh <- i
+	}
+	close(searchIndexCh)
+	wg.Wait()
+
+	searchErrors := make([]error, 0, 10)
+	for i, errors := range queryErrors {
+		for _, err := range errors {
+			searchErrors = append(searchErrors, fmt.Errorf("query error: %v\nsite=[%v],name=[%v]", err, searches[i].site.Name, queries[i].Name))
+		}
+	}
+
+	return mergeResults(queries, queryResults), searchErrors
+}
+
+func mergeResults(queries NamedQueries, queryResults []IssueSearchResults) IssueSearchResults {
+
+	type position struct {
+		result int
+		issue  int
+	}
+	positions := map[string]position{}
+
+	results := make(IssueSearchResults, 0, 10)
+	for i, pages := range queryResults {
+		for _, page := range pages {
+			issues := make(Issues, 0, len(page.Issues))
+			for _, issue := range page.Issues {
+				if p, ok := positions[issue.siteKey()]; ok {
+					var existing *Issue
+					if p.result < len(results) {
+						existing = &results[p.result].Issues[p.issue]
+					} else {
+						existing = &issues[p.issue]
+					}
+					if existing.QueryNames[len(existing.QueryNames)-1] != queries[i].Name {
+						existing.QueryNames = append(existing.QueryNames, queries[i].Name)
+					}
+					continue
+				}
+
+				issue.QueryNames = []string{queries[i].Name}
+				positions[issue.siteKey()] = position{result: len(results), issue: len(issues)}
+				issues = append(issues, issue)
+			}
+
+			page.Issues = issues
+			results = append(results, page)
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,110 @@
+package jira
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNamedQueryFlag_Set(t *testing.T) {
+	var queries NamedQueries
+	queryFlag := &namedQueryFlag{queries: &queries}
+	filterFlag := &namedQueryFlag{queries: &queries, filter: true}
+
+	if err := queryFlag.Set("alpha=project = ALPHA AND status = Closed"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := filterFlag.Set("beta=10001"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	for _, value := range []string{"project = ALPHA", "=10001", "beta="} {
+		if err := queryFlag.Set(value); err == nil {
+			t.Errorf("Set(%v) error = nil, want error", value)
+		}
+	}
+
+	expected := NamedQueries{
+		{Name: "alpha", Query: "project = ALPHA AND status = Closed"},
+		{Name: "beta", Filter: "10001"},
+	}
+	if !reflect.DeepEqual(expected, queries) {
+		t.Errorf("expected=[%v] <> actual=[%v]\n", expected, queries)
+	}
+}
+
+func TestMergeResults(t *testing.T) {
+	queries := NamedQueries{{Name: "alpha"}, {Name: "beta"}}
+	queryResults := []IssueSearchResults{
+		{
+			{Total: 2, Issues: Issues{{Key: "TEST-1"}, {Key: "TEST-2"}}},
+		},
+		{
+			{Total: 3, Issues: Issues{{Key: "TEST-2"}, {Key: "TEST-3"}}},
+			{Total: 3, StartAt: 2, Issues: Issues{{Key: "TEST-3"}}},
+		},
+	}
+
+	results := mergeResults(queries, queryResults)
+
+	actual := map[string][]string{}
+	count := 0
+	for _, result := range results {
+		for _, issue := range result.Issues {
+			actual[issue.Key] = issue.QueryNames
+			count++
+		}
+	}
+
+	expected := map[string][]string{
+		"TEST-1": {"alpha"},
+		"TEST-2": {"alpha", "beta"},
+		"TEST-3": {"beta"},
+	}
+	if count != 3 || !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected=[%v] <> actual=[%v]\n", expected, actual)
+	}
+}
+
+func TestIssueSearch_MaxConcurrentRequests(t *testing.T) {
+	server := setupFakeJira(t)
+	server.Latency = 20 * time.Millisecond
+	config.MaxResult = 1
+
+	for i := 0; i < 3*maxWorkerSize; i++ {
+		config.Queries = append(config.Queries, NamedQuery{Name: fmt.Sprintf("q%02d", i), Query: fmt.Sprintf("project = DEMO AND key != DEMO-%d", 100+i)})
+	}
+
+	results, searchErrors := IssueSearch(config.MaxResult)
+	if len(searchErrors) > 0 {
+		t.Fatalf("IssueSearch error = %v", searchErrors)
+	}
+	if len(results.issueMap()) != 3 {
+		t.Errorf("expected=[%v] <> actual[%v]\n", 3, len(results.issueMap()))
+	}
+	if actual := server.MaxConcurrentRequests(); actual > maxWorkerSize {
+		t.Errorf("expected=[<= %v] <> actual[%v]\n", maxWorkerSize, actual)
+	}
+}
+
+func TestIssueSearch_MissingFilter(t *testing.T) {
+	server := setupFakeJira(t)
+	config.Queries = NamedQueries{{Name: "demo", Filter: "10000"}, {Name: "missing", Filter: "99999"}}
+
+	results, searchErrors := IssueSearch(config.MaxResult)
+	if len(searchErrors) != 1 {
+		t.Fatalf("expected=[1] <> actual[%v]\n", searchErrors)
+	}
+	if !strings.Contains(searchErrors[0].Error(), "name=[missing]") {
+		t.Errorf("expected=[name=[missing]] <> actual[%v]\n", searchErrors[0])
+	}
+	for _, issue := range results.issueMap() {
+		if !reflect.DeepEqual([]string{"demo"}, issue.QueryNames) {
+			t.Errorf("expected=[%v] <> actual[%v]\n", []string{"demo"}, issue.QueryNames)
+		}
+	}
+	if actual := server.Requests("search/jql"); actual != 2 {
+		t.Errorf("expected=[%v] <> actual[%v]\n", 2, actual)
+	}
+}
@@ -0,0 +1,246 @@
+package jira
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const manifestFileName = "manifest.json"
+
+type RecordManifest struct {
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+type RecordedRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int             `json:"statusCode"`
+	Body       json.RawMessage `json:"body"`
+}
+
+type RecordedExchange struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type recordTransport struct {
+	dir  string
+	base http.RoundTripper
+}
+
+type replayTransport struct {
+	dir string
+}
+
+var (
+	defaultTransport = http.DefaultTransport
+	recordMutex      = &sync.Mutex{}
+)
+
+func httpClient() *http.Client {
+
+	return &http.Client{Transport: config.transport()}
+}
+
+func (c *Config) transport() http.RoundTripper {
+
+	switch {
+	case len(c.Replay) > 0:
+		return &replayTransport{dir: c.Replay}
+	case len(c.Record) > 0:
+		return c.retryTransport(&recordTransport{dir: c.Record, base: defaultTransport})
+	}
+
+	return c.retryTransport(defaultTransport)
+}
+
+func (c *Config) retryTransport(base http.RoundTripper) http.RoundTripper {
+
+	if c.MaxRetry <= 0 {
+		return base
+	}
+
+	return &retryTransport{base: base, maxRetry: c.MaxRetry}
+}
+
+func jsonBody(body []byte) json.RawMessage {
+
+	if len(body) == 0 {
+		return nil
+	}
+
+	if json.Valid(body) {
+		return json.RawMessage(body)
+	}
+
+	encoded, _ := json.Marshal(string(body))
+	return json.RawMessage(encoded)
+}
+
+func requestBody(req *http.Request) ([]byte, error) {
+
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ioutil.ReadAll error: %v\nreq=[%v]", err, req)
+	}
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+
+	return body, nil
+}
+
+func recordKey(method string, rawURL string, body []byte) string {
+
+	normalized := body
+	var v map[string]interface{}
+	if err := json.Unmarshal(body, &v); err == nil {
+		normalized, _ = json.Marshal(v)
+	}
+
+	sum := sha256.Sum256([]byte(method + " " + rawURL + "\n" + string(normalized)))
+	return hex.EncodeToString(sum[:])
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	body, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ioutil.ReadAll error: %v\nresp.Body=[%v]", err, resp.Body)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewBuffer(responseBody))
+
+	exchange := RecordedExchange{
+		Request:  RecordedRequest{Method: req.Method, URL: req.URL.String(), Body: jsonBody(body)},
+		Response: RecordedResponse{StatusCode: resp.StatusCode, Body: jsonBody(responseBody)},
+	}
+	if err := t.save(recordKey(req.Method, req.URL.String(), body), exchange); err != nil {
+		log.Printf("record error: %v\nurl=[%v]\n", err, req.URL)
+	}
+
+	return resp, nil
+}
+
+func (t *recordTransport) save(key string, exchange RecordedExchange) error {
+
+	recordMutex.Lock()
+	defer recordMutex.Unlock()
+
+	content, err := json.MarshalIndent(exchange, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json.MarshalIndent error: %v", err)
+	}
+
+	name := filepath.Join(t.dir, key+".json")
+	if err := ioutil.WriteFile(name, content, 0644); err != nil {
+		return fmt.Errorf("ioutil.WriteFile error: %v\nname=[%v]", err, name)
+	}
+
+	return nil
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	body, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	name := filepath.Join(t.dir, recordKey(req.Method, req.URL.String(), body)+".json")
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("no recorded response: %v\nmethod=[%v],url=[%v]", err, req.Method, req.URL)
+	}
+
+	var exchange RecordedExchange
+	if err := json.Unmarshal(content, &exchange); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error: %v\nname=[%v]", err, name)
+	}
+
+	responseBody := []byte(exchange.Response.Body)
+	var text string
+	if err := json.Unmarshal(exchange.Response.Body, &text); err == nil {
+		responseBody = []byte(text)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", exchange.Response.StatusCode, http.StatusText(exchange.Response.StatusCode)),
+		StatusCode:    exchange.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewBuffer(responseBody)),
+		ContentLength: int64(len(responseBody)),
+		Request:       req,
+	}, nil
+}
+
+func (c *Config) startRecord() error {
+
+	if err := os.MkdirAll(c.Record, 0755); err != nil {
+		return fmt.Errorf("os.MkdirAll error: %v\nRecord=[%v]", err, c.Record)
+	}
+
+	content, err := json.MarshalIndent(RecordManifest{RecordedAt: c.clock()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json.MarshalIndent error: %v", err)
+	}
+
+	name := filepath.Join(c.Record, manifestFileName)
+	if err := ioutil.WriteFile(name, content, 0644); err != nil {
+		return fmt.Errorf("ioutil.WriteFile error: %v\nname=[%v]", err, name)
+	}
+
+	return nil
+}
+
+func (c *Config) startReplay() error {
+
+	name := filepath.Join(c.Replay, manifestFileName)
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("ioutil.ReadFile error: %v\nname=[%v]", err, name)
+	}
+
+	var manifest RecordManifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return fmt.Errorf("json.Unmarshal error: %v\nname=[%v]", err, name)
+	}
+
+	recordedAt := manifest.RecordedAt
+	c.clock = func() time.Time {
+		return recordedAt
+	}
+	log.Printf("replay: dir=[%v],recordedAt=[%v]\n", c.Replay, recordedAt)
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package jira
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "record")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := setupFakeJira(t)
+	config.Worklog = true
+	config.Record = dir
+	if err := config.startRecord(); err != nil {
+		t.Fatalf("startRecord() error = %v", err)
+	}
+	recorded := searchAndReport(t)
+	server.Close()
+	cache.clear()
+
+	config.Record = ""
+	config.Replay = dir
+	os.Unsetenv("AUTH_TOKEN")
+	if err := config.startReplay(); err != nil {
+		t.Fatalf("startReplay() error = %v", err)
+	}
+	if replayed := searchAndReport(t); replayed != recorded {
+		t.Errorf("expected=[%v] <> actual[%v]\n", recorded, replayed)
+	}
+
+	config.TimeUnit = "dd"
+	expected := "DEMO-1,2020-08-03T09:00:00.000+0900,Alice,alice@example.com,5b10a2844c20165700ede21g,1.00\n"
+	if replayed := searchAndReport(t); !strings.Contains(replayed, expected) {
+		t.Errorf("expected=[%v] <> actual[%v]\n", expected, replayed)
+	}
+
+	config.Report = "status"
+	if _, _, searchErrors := Search(); len(searchErrors) == 0 {
+		t.Errorf("Search() errors = nil, want errors")
+	}
+
+	config.Report = defaultReport
+	config.Query = "project = OPS"
+	if _, _, searchErrors := Search(); len(searchErrors) == 0 {
+		t.Errorf("Search() errors = nil, want errors")
+	}
+}
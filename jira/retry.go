@@ -0,0 +1,83 @@
+package jira
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxRetry = 3
+	maxRetryWait    = time.Minute
+)
+
+type retryTransport struct {
+	base     http.RoundTripper
+	maxRetry int
+}
+
+func retryable(statusCode int) bool {
+
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+func retryWait(resp *http.Response, attempt int) time.Duration {
+
+	wait := time.Second << attempt
+	value := resp.Header.Get("Retry-After")
+	if second, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(second) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		wait = time.Until(t)
+	}
+
+	if wait < 0 {
+		return 0
+	}
+	if wait > maxRetryWait {
+		return maxRetryWait
+	}
+
+	return wait
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil || !retryable(resp.StatusCode) || attempt >= t.maxRetry {
+			return resp, err
+		}
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+
+		wait := retryWait(resp, attempt)
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		log.Printf("retry: status=[%v],wait=[%v],url=[%v]\n", resp.Status, wait, req.URL)
+		config.progress.retry("", fmt.Sprintf("%v: %v", resp.Status, req.URL.Path))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("req.GetBody error: %v\nurl=[%v]", err, req.URL)
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package jira
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryWait(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		attempt    int
+		expected   time.Duration
+	}{
+		{name: "seconds", retryAfter: "5", expected: 5 * time.Second},
+		{name: "past date", retryAfter: "Wed, 21 Oct 2015 07:28:00 GMT", expected: 0},
+		{name: "too long", retryAfter: "3600", expected: maxRetryWait},
+		{name: "backoff", retryAfter: "", attempt: 2, expected: 4 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if len(tt.retryAfter) > 0 {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+			if actual := retryWait(resp, tt.attempt); actual != tt.expected {
+				t.Errorf("expected=[%v] <> actual[%v]\n", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package jira
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	roundEntry         = "entry"
+	roundTotal         = "total"
+	roundNearest       = "nearest"
+	roundUp            = "up"
+	roundDown          = "down"
+	roundEpsilon       = 1e-9
+	defaultRoundMode   = roundEntry
+	defaultRoundMethod = roundNearest
+	defaultPrecision   = 2
+)
+
+type TimeTotal struct {
+	seconds int
+	steps   int64
+}
+
+func NewTimeTotal(second int) TimeTotal {
+
+	var t TimeTotal
+	t.Add(second)
+	return t
+}
+
+func (t TimeTotal) Minus(o TimeTotal) TimeTotal {
+
+	return TimeTotal{seconds: t.seconds - o.seconds, steps: t.steps - o.steps}
+}
+
+func (t TimeTotal) Plus(o TimeTotal) TimeTotal {
+
+	return TimeTotal{seconds: t.seconds + o.seconds, steps: t.steps + o.steps}
+}
+
+func (t *TimeTotal) Add(second int) {
+
+	t.seconds += second
+	t.steps += config.steps(second, config.RoundMode != roundTotal)
+}
+
+func (t *TimeTotal) Seconds() int {
+
+	return t.seconds
+}
+
+func (t *TimeTotal) String() string {
+
+	if config.RoundMode == roundTotal {
+		return config.formatSteps(config.steps(t.seconds, true), true)
+	}
+
+	return config.formatSteps(t.steps, true)
+}
+
+func (c *Config) validateRounding() error {
+
+	switch c.RoundMode {
+	case roundEntry, roundTotal:
+	default:
+		return fmt.Errorf("unknown rounding mode: %v", c.RoundMode)
+	}
+
+	switch strings.ToLower(c.RoundMethod) {
+	case roundNearest, roundUp, roundDown:
+	default:
+		return fmt.Errorf("unknown rounding method: %v", c.RoundMethod)
+	}
+
+	if c.RoundIncrement < 0 {
+		return fmt.Errorf("negative rounding increment: %v", c.RoundIncrement)
+	}
+
+	if c.Precision < 0 {
+		return fmt.Errorf("negative precision: %v", c.Precision)
+	}
+
+	return nil
+}
+
+func (c *Config) roundingStep(increment bool) float64 {
+
+	if increment && c.RoundIncrement > 0 {
+		return c.RoundIncrement
+	}
+
+	return math.Pow10(-c.Precision)
+}
+
+func (c *Config) steps(second int, increment bool) int64 {
+
+	v := c.WithTimeUnit(second) / c.roundingStep(increment)
+
+	method := roundNearest
+	if increment {
+		method = strings.ToLower(c.RoundMethod)
+	}
+
+	switch method {
+	case roundUp:
+		return int64(math.Ceil(v - roundEpsilon))
+	case roundDown:
+		return int64(math.Floor(v + roundEpsilon))
+	default:
+		return int64(math.Round(v))
+	}
+}
+
+func (c *Config) formatSteps(steps int64, increment bool) string {
+
+	return c.formatNumber(float64(steps) * c.roundingStep(increment))
+}
+
+func (c *Config) formatNumber(v float64) string {
+
+	return strconv.FormatFloat(v, 'f', c.Precision, 64)
+}
+
+func (c *Config) FormatTime(second int) string {
+
+	increment := c.RoundMode != roundTotal
+	return c.formatSteps(c.steps(second, increment), increment)
+}
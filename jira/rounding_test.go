@@ -0,0 +1,55 @@
+package jira
+
+import "testing"
+
+func TestTimeTotal_String(t *testing.T) {
+	defer func() {
+		config.TimeUnit = "dd"
+		config.RoundMode = defaultRoundMode
+		config.RoundMethod = defaultRoundMethod
+		config.RoundIncrement = 0
+		config.Precision = defaultPrecision
+	}()
+
+	entries := []int{20 * 60, 20 * 60, 20 * 60}
+
+	tests := []struct {
+		name      string
+		mode      string
+		method    string
+		increment float64
+		precision int
+		wantEntry string
+		wantTotal string
+	}{
+		{name: "entry nearest", mode: roundEntry, method: roundNearest, precision: 2, wantEntry: "0.33", wantTotal: "0.99"},
+		{name: "total nearest", mode: roundTotal, method: roundNearest, precision: 2, wantEntry: "0.33", wantTotal: "1.00"},
+		{name: "entry up 0.25", mode: roundEntry, method: roundUp, increment: 0.25, precision: 2, wantEntry: "0.50", wantTotal: "1.50"},
+		{name: "total up 0.25", mode: roundTotal, method: roundUp, increment: 0.25, precision: 2, wantEntry: "0.33", wantTotal: "1.00"},
+		{name: "entry down 0.25", mode: roundEntry, method: roundDown, increment: 0.25, precision: 1, wantEntry: "0.2", wantTotal: "0.8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.TimeUnit = "hh"
+			config.RoundMode = tt.mode
+			config.RoundMethod = tt.method
+			config.RoundIncrement = tt.increment
+			config.Precision = tt.precision
+			if err := config.validateRounding(); err != nil {
+				t.Fatalf("validateRounding() error = %v", err)
+			}
+
+			var total TimeTotal
+			for _, second := range entries {
+				total.Add(second)
+			}
+
+			if got := config.FormatTime(entries[0]); got != tt.wantEntry {
+				t.Errorf("FormatTime() = %v, want %v", got, tt.wantEntry)
+			}
+			if got := total.String(); got != tt.wantTotal {
+				t.Errorf("String() = %v, want %v", got, tt.wantTotal)
+			}
+		})
+	}
+}
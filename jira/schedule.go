@@ -0,0 +1,406 @@
+package jira
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	formatCsv     = "csv"
+	formatJson    = "json"
+	defaultFormat = formatCsv
+	runSucceeded  = "success"
+	runFailed     = "failure"
+	maxRunMessage = 1000
+)
+
+type ScheduleDestination struct {
+	Dir     string `yaml:"dir"`
+	Mail    string `yaml:"mail"`
+	Webhook string `yaml:"webhook"`
+}
+
+type Schedule struct {
+	Name        string              `yaml:"name"`
+	Cron        string              `yaml:"cron"`
+	Query       string              `yaml:"query"`
+	Params      map[string]string   `yaml:"params"`
+	Format      string              `yaml:"format"`
+	Subject     string              `yaml:"subject"`
+	Destination ScheduleDestination `yaml:"destination"`
+	Notify      []NotifyTarget      `yaml:"notify"`
+	Link        string              `yaml:"link"`
+	cron        *Cron
+}
+
+type ScheduleFile struct {
+	Schedules []Schedule `yaml:"schedules"`
+}
+
+type Run struct {
+	Id          int
+	Schedule    string
+	StartedAt   time.Time
+	FinishedAt  time.Time
+	Status      string
+	Destination string
+	Message     string
+}
+
+func LoadSchedules(r io.Reader) ([]Schedule, error) {
+
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ioutil.ReadAll error: %v", err)
+	}
+
+	var scheduleFile ScheduleFile
+	if err := yaml.Unmarshal(body, &scheduleFile); err != nil {
+		return nil, fmt.Errorf("yaml.Unmarshal error: %v\nbody=[%v]", err, string(body))
+	}
+
+	names := map[string]bool{}
+	for i := range scheduleFile.Schedules {
+		schedule := &scheduleFile.Schedules[i]
+		if len(schedule.Name) == 0 {
+			return nil, fmt.Errorf("empty schedule name\ncron=[%v]", schedule.Cron)
+		}
+		if names[schedule.Name] {
+			return nil, fmt.Errorf("duplicate schedule name\nschedule=[%v]", schedule.Name)
+		}
+		names[schedule.Name] = true
+
+		cron, err := ParseCron(schedule.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("ParseCron error: %v\nschedule=[%v]", err, schedule.Name)
+		}
+		schedule.cron = cron
+
+		schedule.Format = strings.ToLower(schedule.Format)
+		switch schedule.Format {
+		case "":
+			schedule.Format = defaultFormat
+		case formatCsv, formatJson:
+		default:
+			return nil, fmt.Errorf("unknown format: %v\nschedule=[%v]", schedule.Format, schedule.Name)
+		}
+
+		if err := schedule.Destination.validate(); err != nil {
+			return nil, fmt.Errorf("%v\nschedule=[%v]", err, schedule.Name)
+		}
+
+		for _, target := range schedule.Notify {
+			if err := target.validate(); err != nil {
+				return nil, fmt.Errorf("%v\nschedule=[%v]", err, schedule.Name)
+			}
+		}
+	}
+
+	return scheduleFile.Schedules, nil
+}
+
+func (c *Config) schedules() ([]Schedule, error) {
+
+	if len(c.Schedules) == 0 {
+		return []Schedule{}, nil
+	}
+
+	cacheKey := fmt.Sprintf("schedules_%s", c.Schedules)
+	if v, ok := cache.get(cacheKey); ok {
+		return v.([]Schedule), nil
+	}
+
+	f, err := os.Open(c.Schedules)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open error: %v\nSchedules=[%v]", err, c.Schedules)
+	}
+	defer f.Close()
+
+	schedules, err := LoadSchedules(f)
+	if err != nil {
+		return nil, fmt.Errorf("LoadSchedules error: %v\nSchedules=[%v]", err, c.Schedules)
+	}
+
+	cache.put(cacheKey, schedules)
+	return schedules, nil
+}
+
+func Schedules() ([]Schedule, error) {
+
+	c := currentConfig()
+	return c.schedules()
+}
+
+func (s *Schedule) Next(after time.Time) (time.Time, bool) {
+
+	return s.cron.Next(after)
+}
+
+func (s *Schedule) queryParams() url.Values {
+
+	queryParams := url.Values{}
+	for key, value := range s.Params {
+		queryParams.Set(key, value)
+	}
+
+	return queryParams
+}
+
+func (s *Schedule) fileName(t time.Time) string {
+
+	return fmt.Sprintf("%s-%s.%s", s.Name, t.Format("20060102-1504"), s.Format)
+}
+
+func (d *ScheduleDestination) validate() error {
+
+	n := 0
+	for _, v := range []string{d.Dir, d.Mail, d.Webhook} {
+		if len(v) > 0 {
+			n++
+		}
+	}
+	if n != 1 {
+		return fmt.Errorf("destination must have one of dir, mail or webhook")
+	}
+
+	return nil
+}
+
+func (d *ScheduleDestination) String() string {
+
+	switch {
+	case len(d.Dir) > 0:
+		return "dir:" + d.Dir
+	case len(d.Mail) > 0:
+		return "mail:" + d.Mail
+	}
+
+	return "webhook:" + d.Webhook
+}
+
+func (s *Schedule) deliver(t time.Time, body []byte, summary *ReportSummary) error {
+
+	switch {
+	case len(s.Destination.Dir) > 0:
+		return deliverFile(s.Destination.Dir, s.fileName(t), body)
+	case len(s.Destination.Mail) > 0:
+		subject, err := mailSubject(s.Subject, summary)
+		if err != nil {
+			return err
+		}
+		return config.sendMail(&Mail{
+			To:          mailRecipients(s.Destination.Mail),
+			Subject:     subject,
+			Summary:     summary,
+			FileName:    s.fileName(t),
+			ContentType: contentType(s.Format),
+			Body:        body,
+		})
+	}
+
+	return deliverWebhook(s.Destination.Webhook, contentType(s.Format), body)
+}
+
+func deliverFile(dir string, name string, body []byte) error {
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("os.MkdirAll error: %v\ndir=[%v]", err, dir)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, body, 0644); err != nil {
+		return fmt.Errorf("ioutil.WriteFile error: %v\npath=[%v]", err, path)
+	}
+
+	return nil
+}
+
+func deliverWebhook(webhookURL string, contentType string, body []byte) error {
+
+	req, err := http.NewRequest("POST", webhookURL, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("http.NewRequest error: %v\nwebhookURL=[%v]", err, webhookURL)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	client := &http.Client{Transport: defaultTransport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("client.Do error: %v\nwebhookURL=[%v]", err, webhookURL)
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("ioutil.ReadAll error: %v\nresp.Body=[%v]", err, resp.Body)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %v\nresponseBody=[%v]", resp.Status, string(responseBody))
+	}
+
+	return nil
+}
+
+func (s *Schedule) render() ([]byte, *ReportSummary, []error) {
+
+	var buf bytes.Buffer
+	var summary *ReportSummary
+	renderErrors := make([]error, 0, 10)
+	err := WithQueryParams(s.queryParams(), func() {
+		if len(s.Query) > 0 {
+			queries := make(NamedQueries, 0, 1)
+			for _, q := range config.namedQueries() {
+				if q.Name == s.Query {
+					queries = append(queries, q)
+				}
+			}
+			if len(queries) == 0 {
+				renderErrors = append(renderErrors, fmt.Errorf("unknown query: %v", s.Query))
+				return
+			}
+			config.Queries = queries
+		}
+
+		cache.clear()
+		issues, worklogs, searchErrors := Search()
+		if len(searchErrors) > 0 {
+			renderErrors = append(renderErrors, searchErrors...)
+			return
+		}
+		summary = newReportSummary(s.Name, issues, worklogs)
+
+		if s.Format == formatJson {
+			if err := NewExport(issues, worklogs).RenderJson(&buf); err != nil {
+				renderErrors = append(renderErrors, err)
+			}
+			return
+		}
+		renderErrors = append(renderErrors, Report(&buf, issues, worklogs)...)
+	})
+	if err != nil {
+		renderErrors = append(renderErrors, err)
+	}
+
+	return buf.Bytes(), summary, renderErrors
+}
+
+func (s *Schedule) Run() *Run {
+
+	clock := currentConfig().clock
+	run := &Run{Schedule: s.Name, StartedAt: clock(), Destination: s.Destination.String(), Status: runSucceeded}
+
+	body, summary, renderErrors := s.render()
+	messages := make([]string, 0, len(renderErrors)+1)
+	for _, err := range renderErrors {
+		messages = append(messages, err.Error())
+	}
+
+	if len(body) == 0 || len(renderErrors) > 0 {
+		run.Status = runFailed
+	} else if err := s.deliver(run.StartedAt, body, summary); err != nil {
+		run.Status = runFailed
+		messages = append([]string{err.Error()}, messages...)
+	} else {
+		if len(s.Link) > 0 {
+			summary.Link = strings.TrimSuffix(s.Link, "/") + "/" + s.fileName(run.StartedAt)
+		}
+		for _, err := range notify(s.Notify, summary) {
+			run.Status = runFailed
+			messages = append(messages, err.Error())
+		}
+	}
+
+	run.Message = strings.Join(messages, "\n")
+	if len(run.Message) > maxRunMessage {
+		run.Message = run.Message[:maxRunMessage]
+	}
+	run.FinishedAt = clock()
+
+	return run
+}
+
+func (s *Store) PutRun(run *Run) error {
+
+	result, err := s.db.Exec("INSERT INTO runs (schedule, started_at, finished_at, status, destination, message) VALUES (?, ?, ?, ?, ?, ?)",
+		run.Schedule, run.StartedAt.Format(storeTimeLayout), run.FinishedAt.Format(storeTimeLayout), run.Status, run.Destination, run.Message)
+	if err != nil {
+		return fmt.Errorf("db.Exec error: %v\nschedule=[%v]", err, run.Schedule)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("result.LastInsertId error: %v", err)
+	}
+	run.Id = int(id)
+
+	return nil
+}
+
+func (s *Store) Runs(limit int) ([]Run, error) {
+
+	rows, err := s.db.Query("SELECT id, schedule, started_at, finished_at, status, destination, message FROM runs ORDER BY id DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, fmt.Errorf("db.Query error: %v", err)
+	}
+	defer rows.Close()
+
+	runs := make([]Run, 0, 10)
+	for rows.Next() {
+		var run Run
+		var startedAt, finishedAt string
+		if err := rows.Scan(&run.Id, &run.Schedule, &startedAt, &finishedAt, &run.Status, &run.Destination, &run.Message); err != nil {
+			return nil, fmt.Errorf("rows.Scan error: %v", err)
+		}
+		if run.StartedAt, err = time.Parse(storeTimeLayout, startedAt); err != nil {
+			return nil, fmt.Errorf("time.Parse error: %v\nstartedAt=[%v]", err, startedAt)
+		}
+		if run.FinishedAt, err = time.Parse(storeTimeLayout, finishedAt); err != nil {
+			return nil, fmt.Errorf("time.Parse error: %v\nfinishedAt=[%v]", err, finishedAt)
+		}
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err error: %v", err)
+	}
+
+	return runs, nil
+}
+
+func RunSchedule(schedule Schedule) (*Run, error) {
+
+	run := schedule.Run()
+
+	store, err := OpenStore(currentConfig().Database)
+	if err != nil {
+		return run, fmt.Errorf("OpenStore error: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.PutRun(run); err != nil {
+		return run, fmt.Errorf("store.PutRun error: %v", err)
+	}
+
+	return run, nil
+}
+
+func Runs(limit int) ([]Run, error) {
+
+	store, err := OpenStore(currentConfig().Database)
+	if err != nil {
+		return nil, fmt.Errorf("OpenStore error: %v", err)
+	}
+	defer store.Close()
+
+	return store.Runs(limit)
+}
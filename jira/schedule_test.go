@@ -0,0 +1,191 @@
+package jira
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadSchedules(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			input: `schedules:
+  - name: monthly
+    cron: "0 9 1 * *"
+    params:
+      targetyearmonth: "2020-08"
+    destination:
+      dir: reports
+  - name: weekly
+    cron: "0 9 * * 1"
+    format: json
+    destination:
+      webhook: https://example.com/hook
+`,
+		},
+		{
+			name: "duplicate name",
+			input: `schedules:
+  - {name: monthly, cron: "0 9 1 * *", destination: {dir: reports}}
+  - {name: monthly, cron: "0 9 2 * *", destination: {dir: reports}}
+`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid cron",
+			input:   `schedules: [{name: monthly, cron: "0 9 1 *", destination: {dir: reports}}]`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown format",
+			input:   `schedules: [{name: monthly, cron: "0 9 1 * *", format: xml, destination: {dir: reports}}]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid notify",
+			input:   `schedules: [{name: monthly, cron: "0 9 1 * *", destination: {dir: reports}, notify: [{slack: a, teams: b}]}]`,
+			wantErr: true,
+		},
+		{
+			name:    "no destination",
+			input:   `schedules: [{name: monthly, cron: "0 9 1 * *"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "two destinations",
+			input:   `schedules: [{name: monthly, cron: "0 9 1 * *", destination: {dir: reports, mail: a@example.com}}]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedules, err := LoadSchedules(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadSchedules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (len(schedules) != 2 || schedules[0].Format != formatCsv || schedules[1].Format != formatJson) {
+				t.Errorf("expected=[csv,json] <> actual[%v]\n", schedules)
+			}
+		})
+	}
+}
+
+func TestRunSchedule(t *testing.T) {
+	server := setupFakeJira(t)
+	config.Database = filepath.Join(t.TempDir(), "store.db")
+	config.clock = func() time.Time { return time.Date(2020, 9, 1, 9, 0, 0, 0, time.UTC) }
+	dir := t.TempDir()
+	smtpServer := setupSMTPServer(t)
+	chat := setupWebhookStandIn(t)
+
+	var received string
+	var contentType string
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received = string(body)
+		contentType = r.Header.Get("Content-Type")
+	}))
+	defer webhook.Close()
+
+	schedules, err := LoadSchedules(strings.NewReader(`schedules:
+  - name: monthly
+    cron: "0 9 1 * *"
+    params:
+      timeunit: dd
+    destination:
+      dir: ` + dir + `
+    link: https://files.example.com/reports/
+    notify:
+      - slack: ` + chat.URL + `/slack
+        template: "{{.Name}} {{.Link}}"
+  - name: hook
+    cron: "0 9 1 * *"
+    format: json
+    destination:
+      webhook: ` + webhook.URL + `
+  - name: mail
+    cron: "0 9 1 * *"
+    subject: "{{.TargetMonth}} {{.Name}}"
+    destination:
+      mail: finance@example.com
+  - name: missing
+    cron: "0 9 1 * *"
+    query: missing
+    destination:
+      dir: ` + dir + `
+`))
+	if err != nil {
+		t.Fatalf("LoadSchedules() error = %v", err)
+	}
+
+	for _, schedule := range schedules {
+		if _, err := RunSchedule(schedule); err != nil {
+			t.Fatalf("RunSchedule() error = %v", err)
+		}
+	}
+	if config.TimeUnit != "hh" {
+		t.Errorf("expected=[hh] <> actual[%v]\n", config.TimeUnit)
+	}
+
+	body, err := ioutil.ReadFile(filepath.Join(dir, "monthly-20200901-0900.csv"))
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile() error = %v", err)
+	}
+	if !strings.HasPrefix(string(body), "キー,") {
+		t.Errorf("expected=[キー,] <> actual[%v]\n", string(body))
+	}
+	if contentType != "application/json" || !strings.Contains(received, `"issues"`) {
+		t.Errorf("expected=[application/json] <> actual[%v,%v]\n", contentType, received)
+	}
+
+	if actual := chat.payload("/slack")["text"]; actual != "monthly https://files.example.com/reports/monthly-20200901-0900.csv" {
+		t.Errorf("expected=[monthly link] <> actual[%v]\n", actual)
+	}
+
+	mails := smtpServer.Mails()
+	if len(mails) != 1 || !strings.Contains(mails[0].Data, "Subject: 2020-08 mail\n") || !strings.Contains(mails[0].Data, `filename=mail-20200901-0900.csv`) {
+		t.Errorf("expected=[mail-20200901-0900.csv] <> actual[%v]\n", mails)
+	}
+
+	searched := server.Requests("search/jql")
+	config.clock = func() time.Time { return time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC) }
+	if _, err := RunSchedule(schedules[0]); err != nil {
+		t.Fatalf("RunSchedule() error = %v", err)
+	}
+	if server.Requests("search/jql") == searched {
+		t.Errorf("expected=[search again] <> actual[%v]\n", server.Requests("search/jql"))
+	}
+
+	server.FailNext(100)
+	config.clock = func() time.Time { return time.Date(2020, 9, 1, 11, 0, 0, 0, time.UTC) }
+	if _, err := RunSchedule(schedules[0]); err != nil {
+		t.Fatalf("RunSchedule() error = %v", err)
+	}
+	server.FailNext(0)
+	if _, err := os.Stat(filepath.Join(dir, "monthly-20200901-1100.csv")); !os.IsNotExist(err) {
+		t.Errorf("expected=[not delivered] <> actual[%v]\n", err)
+	}
+
+	runs, err := Runs(10)
+	if err != nil {
+		t.Fatalf("Runs() error = %v", err)
+	}
+	actual := make([]string, 0, len(runs))
+	for _, run := range runs {
+		actual = append(actual, run.Schedule+":"+run.Status)
+	}
+	expected := []string{"monthly:failure", "monthly:success", "missing:failure", "mail:success", "hook:success", "monthly:success"}
+	if strings.Join(actual, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected=[%v] <> actual[%v]\n", expected, actual)
+	}
+}
@@ -0,0 +1,202 @@
+package jira
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const (
+	searchApiAuto    = "auto"
+	searchApiJql     = "jql"
+	searchApiLegacy  = "legacy"
+	defaultSearchApi = searchApiAuto
+)
+
+var errSearchNotSupported = errors.New("search endpoint is not supported")
+
+func (c *Config) validateSearchApi() error {
+
+	switch strings.ToLower(c.SearchApi) {
+	case "", searchApiAuto, searchApiJql, searchApiLegacy:
+		return nil
+	}
+
+	return fmt.Errorf("unknown search api: %v", c.SearchApi)
+}
+
+func (s *Site) searchApi() string {
+
+	if len(s.SearchApi) > 0 {
+		return strings.ToLower(s.SearchApi)
+	}
+
+	return strings.ToLower(config.SearchApi)
+}
+
+func (s *Site) legacySearchCacheKey() string {
+
+	return fmt.Sprintf("legacySearch_%s", s.BaseURL)
+}
+
+func (s *Site) enhancedSearch() bool {
+
+	switch s.searchApi() {
+	case searchApiLegacy:
+		return false
+	case searchApiJql:
+		return true
+	}
+
+	_, legacy := cache.get(s.legacySearchCacheKey())
+	return !legacy
+}
+
+func (s *Site) fallbackSearch() bool {
+
+	switch s.searchApi() {
+	case "", searchApiAuto:
+		cache.put(s.legacySearchCacheKey(), true)
+		return true
+	}
+
+	return false
+}
+
+func searchJql(site Site, jql string, nextPageToken string, maxResult int) (*IssueSearchResult, error) {
+
+	searchRequest := map[string]interface{}{
+		"fields":     config.searchFields(),
+		"maxResults": maxResult,
+	}
+	if config.expandChangelog() {
+		searchRequest["expand"] = "changelog"
+	}
+	if len(jql) > 0 {
+		searchRequest["jql"] = jql
+	}
+	if len(nextPageToken) > 0 {
+		searchRequest["nextPageToken"] = nextPageToken
+	}
+
+	log.Printf("search: site=[%v],nextPageToken=[%v],query=[%v]\n", site.Name, nextPageToken, searchRequest["jql"])
+	requestBody, err := json.Marshal(searchRequest)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal error: %v\nsearchRequest=[%v]", err, searchRequest)
+	}
+
+	searchURL, err := site.SearchJqlURL()
+	if err != nil {
+		return nil, fmt.Errorf("site.SearchJqlURL error: %v", err)
+	}
+
+	result, err := getSearchResult(site, searchURL, requestBody)
+	if err == errSearchNotSupported {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getSearchResult error: %v\nrequestBody=[%v]", err, string(requestBody))
+	}
+
+	for i := range result.Issues {
+		result.Issues[i].Site = site.Name
+	}
+	config.progress.page(site.Name, len(result.Issues))
+	config.worklogStream.add(result.Issues)
+
+	return result, nil
+}
+
+func enhancedIssueSearch(site Site, jql string, maxResult int) (IssueSearchResults, []error, bool) {
+
+	results := make(IssueSearchResults, 0, 10)
+	searchErrors := make([]error, 0, 10)
+
+	firstResult, err := searchJql(site, jql, "", maxResult)
+	if err == errSearchNotSupported && site.fallbackSearch() {
+		return nil, nil, false
+	}
+	if err != nil {
+		return results, append(searchErrors, fmt.Errorf("searchJql error: %v", err)), true
+	}
+
+	if len(firstResult.Issues) > 0 {
+		results = append(results, *firstResult)
+	}
+
+	nextPageToken := firstResult.NextPageToken
+	if firstResult.IsLast {
+		nextPageToken = ""
+	}
+	for len(nextPageToken) > 0 {
+		result, err := searchJql(site, jql, nextPageToken, maxResult)
+		if err != nil {
+			return results, append(searchErrors, fmt.Errorf("searchJql error: %v\nnextPageToken=[%v]", err, nextPageToken)), true
+		}
+
+		if len(result.Issues) > 0 {
+			results = append(results, *result)
+		}
+		if result.IsLast {
+			break
+		}
+		nextPageToken = result.NextPageToken
+	}
+
+	return results, searchErrors, true
+}
+
+func getApproximateCount(site Site, jql string) (int, error) {
+
+	countURL, err := site.ApproximateCountURL()
+	if err != nil {
+		return 0, fmt.Errorf("site.ApproximateCountURL error: %v", err)
+	}
+
+	requestBody, err := json.Marshal(map[string]interface{}{"jql": jql})
+	if err != nil {
+		return 0, fmt.Errorf("json.Marshal error: %v\njql=[%v]", err, jql)
+	}
+
+	req, err := http.NewRequest("POST", countURL.String(), bytes.NewBuffer(requestBody))
+	if err != nil {
+		return 0, fmt.Errorf("http.NewRequest error: %v\ncountURL=[%v],requestBody=[%v]",
+			err, countURL, requestBody)
+	}
+
+	req.Header.Set("Authorization", site.basicAuthorization())
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	client := httpClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("client.Do error: %v\nreq=[%v]", err, req)
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("ioutil.ReadAll error: %v\nresp.Body=[%v]", err, resp.Body)
+	}
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return 0, errSearchNotSupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status: %v\nresponseBody=[%v]", resp.Status, string(responseBody))
+	}
+
+	var result struct {
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal(responseBody, &result); err != nil {
+		return 0, fmt.Errorf("json.Unmarshal error: %v\nresponseBody=[%v]", err, responseBody)
+	}
+
+	return result.Count, nil
+}
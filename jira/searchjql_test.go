@@ -0,0 +1,74 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIssueSearch_enhanced(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/3/search/jql", func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+
+		pages := map[string]IssueSearchResult{
+			"":       {Issues: Issues{{Key: "TEST-1"}, {Key: "TEST-2"}}, NextPageToken: "token2"},
+			"token2": {Issues: Issues{{Key: "TEST-3"}, {Key: "TEST-4"}}, NextPageToken: "token3"},
+			"token3": {Issues: Issues{{Key: "TEST-5"}}, IsLast: true},
+		}
+		if err := json.NewEncoder(w).Encode(pages[request.NextPageToken]); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	site := Site{Name: "enhanced", BaseURL: server.URL, ApiVersion: "3", User: "user", Token: "token", SearchApi: searchApiAuto}
+	results, searchErrors := issueSearch(site, NamedQuery{Query: "project = TEST"}, 2)
+	if len(searchErrors) > 0 {
+		t.Fatalf("issueSearch() errors = %v", searchErrors)
+	}
+
+	expected := []string{"TEST-1", "TEST-2", "TEST-3", "TEST-4", "TEST-5"}
+	actual := make([]string, 0, len(expected))
+	for _, result := range results {
+		for _, issue := range result.Issues {
+			actual = append(actual, issue.Key)
+		}
+	}
+	if fmt.Sprint(expected) != fmt.Sprint(actual) {
+		t.Errorf("expected=[%v] <> actual[%v]\n", expected, actual)
+	}
+}
+
+func TestIssueSearch_fallback(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/2/search", func(w http.ResponseWriter, r *http.Request) {
+		result := IssueSearchResult{Total: 1, MaxResults: 50, Issues: Issues{{Key: "DC-1"}}}
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	site := Site{Name: "datacenter", BaseURL: server.URL, ApiVersion: "2", User: "user", Token: "token", SearchApi: searchApiAuto}
+	results, searchErrors := issueSearch(site, NamedQuery{Query: "project = DC"}, 50)
+	if len(searchErrors) > 0 {
+		t.Fatalf("issueSearch() errors = %v", searchErrors)
+	}
+
+	if len(results) != 1 || results[0].Issues[0].Key != "DC-1" || results[0].Issues[0].Site != "datacenter" {
+		t.Errorf("expected=[%v] <> actual[%v]\n", "DC-1", results)
+	}
+	if site.enhancedSearch() {
+		t.Errorf("expected=[%v] <> actual[%v]\n", false, true)
+	}
+}
@@ -0,0 +1,340 @@
+package jira
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Site struct {
+	Name       string `yaml:"name"`
+	BaseURL    string `yaml:"url"`
+	ApiVersion string `yaml:"api"`
+	User       string `yaml:"user"`
+	Token      string `yaml:"token"`
+	SearchApi  string `yaml:"search"`
+}
+
+type SiteFile struct {
+	Sites []Site `yaml:"sites"`
+}
+
+type AuthorMap map[string]string
+
+func LoadSites(r io.Reader) ([]Site, error) {
+
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ioutil.ReadAll error: %v", err)
+	}
+
+	var siteFile SiteFile
+	if err := yaml.Unmarshal(body, &siteFile); err != nil {
+		return nil, fmt.Errorf("yaml.Unmarshal error: %v\nbody=[%v]", err, string(body))
+	}
+
+	names := map[string]bool{}
+	for i := range siteFile.Sites {
+		site := &siteFile.Sites[i]
+		if len(site.Name) == 0 || len(site.BaseURL) == 0 {
+			return nil, fmt.Errorf("empty site name or url\nsite=[%v]", site.Name)
+		}
+		if names[site.Name] {
+			return nil, fmt.Errorf("duplicate site name\nsite=[%v]", site.Name)
+		}
+		names[site.Name] = true
+
+		if len(site.ApiVersion) == 0 {
+			site.ApiVersion = defaultJiraRestApiVersion
+		}
+		site.User = os.ExpandEnv(site.User)
+		site.Token = os.ExpandEnv(site.Token)
+	}
+
+	return siteFile.Sites, nil
+}
+
+func (c *Config) sites() ([]Site, error) {
+
+	if len(c.Sites) == 0 {
+		return []Site{c.defaultSite()}, nil
+	}
+
+	cacheKey := fmt.Sprintf("sites_%s", c.Sites)
+	if v, ok := cache.get(cacheKey); ok {
+		return v.([]Site), nil
+	}
+
+	f, err := os.Open(c.Sites)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open error: %v\nSites=[%v]", err, c.Sites)
+	}
+	defer f.Close()
+
+	sites, err := LoadSites(f)
+	if err != nil {
+		return nil, fmt.Errorf("LoadSites error: %v\nSites=[%v]", err, c.Sites)
+	}
+
+	cache.put(cacheKey, sites)
+	return sites, nil
+}
+
+func (c *Config) defaultSite() Site {
+
+	return Site{
+		BaseURL:    c.BaseURL,
+		ApiVersion: c.ApiVersion,
+		User:       os.Getenv("AUTH_USER"),
+		Token:      os.Getenv("AUTH_TOKEN"),
+	}
+}
+
+func (c *Config) site(name string) (Site, error) {
+
+	sites, err := c.sites()
+	if err != nil {
+		return Site{}, err
+	}
+
+	for _, site := range sites {
+		if site.Name == name {
+			return site, nil
+		}
+	}
+
+	return Site{}, fmt.Errorf("unknown site: %v", name)
+}
+
+func (c *Config) multiSite() bool {
+
+	return len(c.Sites) > 0
+}
+
+func (s *Site) credentials() (string, string) {
+
+	user, token := s.User, s.Token
+	if len(user) == 0 {
+		user = os.Getenv("AUTH_USER")
+	}
+	if len(token) == 0 {
+		token = os.Getenv("AUTH_TOKEN")
+	}
+
+	return user, token
+}
+
+func (s *Site) checkAuth() error {
+
+	user, token := s.credentials()
+	if len(user) == 0 || len(token) == 0 {
+		if len(s.Name) > 0 {
+			return fmt.Errorf("サイト %s の認証情報(user/token または環境変数 AUTH_USER/AUTH_TOKEN)が未定義", s.Name)
+		}
+		return fmt.Errorf("環境変数 AUTH_USER/AUTH_TOKEN が未定義")
+	}
+
+	return nil
+}
+
+func (s *Site) basicAuthorization() string {
+
+	if err := s.checkAuth(); err != nil {
+		if len(config.Replay) > 0 {
+			return ""
+		}
+		panic(err)
+	}
+
+	user, token := s.credentials()
+
+	return fmt.Sprintf("Basic %s", base64.URLEncoding.EncodeToString([]byte(user+":"+token)))
+}
+
+func (s *Site) cacheKey(key string) string {
+
+	return fmt.Sprintf("%s_%s", s.BaseURL, key)
+}
+
+func (s *Site) apiURL(path string) (*url.URL, error) {
+
+	u, err := url.Parse(s.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("url.Parse error: %v\nBaseURL=[%v]", err, s.BaseURL)
+	}
+
+	u.Path = fmt.Sprintf("/rest/api/%s/%s", s.ApiVersion, path)
+
+	return u, nil
+}
+
+func (s *Site) FilterURL(filterID string) (*url.URL, error) {
+
+	return s.apiURL(fmt.Sprintf("filter/%s", filterID))
+}
+
+func (s *Site) SearchURL() (*url.URL, error) {
+
+	return s.apiURL("search")
+}
+
+func (s *Site) SearchJqlURL() (*url.URL, error) {
+
+	return s.apiURL("search/jql")
+}
+
+func (s *Site) ApproximateCountURL() (*url.URL, error) {
+
+	return s.apiURL("search/approximate-count")
+}
+
+func (s *Site) JqlParseURL() (*url.URL, error) {
+
+	u, err := s.apiURL("jql/parse")
+	if err != nil {
+		return nil, err
+	}
+
+	u.RawQuery = url.Values{"validation": []string{"strict"}}.Encode()
+
+	return u, nil
+}
+
+func (s *Site) WorklogURL(key string, queryParams url.Values) (*url.URL, error) {
+
+	u, err := s.apiURL(fmt.Sprintf("issue/%s/worklog", key))
+	if err != nil {
+		return nil, err
+	}
+
+	u.RawQuery = queryParams.Encode()
+
+	return u, nil
+}
+
+func (s *Site) ChangelogURL(key string, queryParams url.Values) (*url.URL, error) {
+
+	u, err := s.apiURL(fmt.Sprintf("issue/%s/changelog", key))
+	if err != nil {
+		return nil, err
+	}
+
+	u.RawQuery = queryParams.Encode()
+
+	return u, nil
+}
+
+func (s *Site) UserURL(accountId string) (*url.URL, error) {
+
+	u, err := s.apiURL("user")
+	if err != nil {
+		return nil, err
+	}
+
+	u.RawQuery = url.Values{"accountId": []string{accountId}}.Encode()
+
+	return u, nil
+}
+
+func (s *Site) GroupMemberURL(groupname string, startAt int, maxResults int) (*url.URL, error) {
+
+	u, err := s.apiURL("group/member")
+	if err != nil {
+		return nil, err
+	}
+
+	u.RawQuery = url.Values{
+		"groupname":  []string{groupname},
+		"startAt":    []string{strconv.Itoa(startAt)},
+		"maxResults": []string{strconv.Itoa(maxResults)},
+	}.Encode()
+
+	return u, nil
+}
+
+func LoadAuthorMap(r io.Reader) (AuthorMap, error) {
+
+	authorMap := AuthorMap{}
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		values := strings.SplitN(line, ",", 2)
+		if len(values) < 2 || len(strings.TrimSpace(values[0])) == 0 || len(strings.TrimSpace(values[1])) == 0 {
+			return nil, fmt.Errorf("invalid author mapping (accountId or emailAddress,emailAddress)\nlineNumber=[%v],line=[%v]", lineNumber, line)
+		}
+		authorMap[strings.TrimSpace(values[0])] = strings.TrimSpace(values[1])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanner.Err error: %v", err)
+	}
+
+	return authorMap, nil
+}
+
+func (c *Config) authorMap() (AuthorMap, error) {
+
+	cacheKey := fmt.Sprintf("authorMap_%s", c.AuthorMap)
+	if v, ok := cache.get(cacheKey); ok {
+		return v.(AuthorMap), nil
+	}
+
+	authorMap := AuthorMap{}
+	if len(c.AuthorMap) > 0 {
+		f, err := os.Open(c.AuthorMap)
+		if err != nil {
+			return nil, fmt.Errorf("os.Open error: %v\nAuthorMap=[%v]", err, c.AuthorMap)
+		}
+		defer f.Close()
+
+		authorMap, err = LoadAuthorMap(f)
+		if err != nil {
+			return nil, fmt.Errorf("LoadAuthorMap error: %v\nAuthorMap=[%v]", err, c.AuthorMap)
+		}
+	}
+
+	cache.put(cacheKey, authorMap)
+	return authorMap, nil
+}
+
+func (m AuthorMap) reconcile(u *User) {
+
+	for _, alias := range []string{u.AccountId, u.Emailaddress} {
+		if len(alias) == 0 {
+			continue
+		}
+		if email, ok := m[alias]; ok {
+			u.Emailaddress = email
+			return
+		}
+	}
+}
+
+func ReconcileAuthors(results WorklogResults) error {
+
+	authorMap, err := config.authorMap()
+	if err != nil {
+		return fmt.Errorf("config.authorMap error: %v", err)
+	}
+
+	for i := range results {
+		for j := range results[i].Worklogs {
+			authorMap.reconcile(&results[i].Worklogs[j].Author)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package jira
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadSites(t *testing.T) {
+	os.Setenv("TEST_SITE_TOKEN", "secret")
+	defer os.Unsetenv("TEST_SITE_TOKEN")
+
+	sites, err := LoadSites(strings.NewReader(`
+sites:
+  - name: bu1
+    url: https://bu1.atlassian.net
+    user: alice@example.com
+    token: ${TEST_SITE_TOKEN}
+  - name: bu2
+    url: https://jira.bu2.example.com
+    api: "2"
+`))
+	if err != nil {
+		t.Fatalf("LoadSites() error = %v", err)
+	}
+
+	expected := []Site{
+		{Name: "bu1", BaseURL: "https://bu1.atlassian.net", ApiVersion: "3", User: "alice@example.com", Token: "secret"},
+		{Name: "bu2", BaseURL: "https://jira.bu2.example.com", ApiVersion: "2"},
+	}
+	if !reflect.DeepEqual(expected, sites) {
+		t.Errorf("expected=[%v] <> actual[%v]\n", expected, sites)
+	}
+
+	searchURL, err := sites[1].SearchURL()
+	if err != nil {
+		t.Fatalf("SearchURL() error = %v", err)
+	}
+	if searchURL.String() != "https://jira.bu2.example.com/rest/api/2/search" {
+		t.Errorf("expected=[%v] <> actual[%v]\n", "https://jira.bu2.example.com/rest/api/2/search", searchURL)
+	}
+
+	for _, body := range []string{
+		"sites:\n  - name: bu1\n",
+		"sites:\n  - name: bu1\n    url: https://a\n  - name: bu1\n    url: https://b\n",
+	} {
+		if _, err := LoadSites(strings.NewReader(body)); err == nil {
+			t.Errorf("LoadSites(%v) error = nil, want error", body)
+		}
+	}
+}
+
+func TestAuthorMap_reconcile(t *testing.T) {
+	authorMap, err := LoadAuthorMap(strings.NewReader("# accountId or emailAddress,emailAddress\n5b10a2844c20165700ede21g,alice@example.com\nalice@bu2.example.com,alice@example.com\n"))
+	if err != nil {
+		t.Fatalf("LoadAuthorMap() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		author User
+		want   string
+	}{
+		{"by accountId", User{AccountId: "5b10a2844c20165700ede21g"}, "alice@example.com"},
+		{"by emailAddress", User{AccountId: "JIRAUSER10000", Emailaddress: "alice@bu2.example.com"}, "alice@example.com"},
+		{"unmapped", User{AccountId: "JIRAUSER10001", Emailaddress: "bob@example.com"}, "bob@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			author := tt.author
+			authorMap.reconcile(&author)
+			if author.Emailaddress != tt.want {
+				t.Errorf("expected=[%v] <> actual[%v]\n", tt.want, author.Emailaddress)
+			}
+		})
+	}
+
+	if _, err := LoadAuthorMap(strings.NewReader("alice@example.com\n")); err == nil {
+		t.Errorf("LoadAuthorMap() error = nil, want error")
+	}
+}
+
+func TestMergeResults_sites(t *testing.T) {
+	queries := NamedQueries{{}, {}}
+	queryResults := []IssueSearchResults{
+		{
+			{Total: 1, Issues: Issues{{Key: "TEST-1", Site: "bu1"}}},
+		},
+		{
+			{Total: 2, Issues: Issues{{Key: "TEST-1", Site: "bu2"}, {Key: "TEST-2", Site: "bu2"}}},
+		},
+	}
+
+	count := 0
+	for _, result := range mergeResults(queries, queryResults) {
+		count += len(result.Issues)
+	}
+
+	if count != 3 {
+		t.Errorf("expected=[%v] <> actual[%v]\n", 3, count)
+	}
+}
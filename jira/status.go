@@ -0,0 +1,390 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type StatusPeriod struct {
+	Status string
+	Start  time.Time
+	End    time.Time
+}
+
+type IssueTimeline struct {
+	Key       string
+	Issuetype string
+	Created   time.Time
+	Resolved  time.Time
+	Started   time.Time
+	Periods   []StatusPeriod
+}
+
+type IssueTimelines []IssueTimeline
+
+type ChangelogResult struct {
+	StartAt    int       `json:"startAt"`
+	MaxResults int       `json:"maxResults"`
+	Total      int       `json:"total"`
+	IsLast     bool      `json:"isLast"`
+	Values     []History `json:"values"`
+}
+
+func parseJiraTime(value string) (time.Time, error) {
+
+	return time.Parse(jiraTimeLayout, value)
+}
+
+func (i *Issue) Timeline(now time.Time) (*IssueTimeline, error) {
+
+	created, err := parseJiraTime(i.Fields.Created)
+	if err != nil {
+		return nil, fmt.Errorf("parseJiraTime error: %v\nkey=[%v],created=[%v]", err, i.Key, i.Fields.Created)
+	}
+
+	timeline := &IssueTimeline{
+		Key:       i.Key,
+		Issuetype: i.Fields.Issuetype.Name,
+		Created:   created,
+		Periods:   make([]StatusPeriod, 0, 5),
+	}
+
+	if len(i.Fields.Resolutiondate) > 0 {
+		resolved, err := parseJiraTime(i.Fields.Resolutiondate)
+		if err != nil {
+			return nil, fmt.Errorf("parseJiraTime error: %v\nkey=[%v],resolutiondate=[%v]", err, i.Key, i.Fields.Resolutiondate)
+		}
+		timeline.Resolved = resolved
+	}
+
+	histories := make([]History, len(i.Changelog.Histories))
+	copy(histories, i.Changelog.Histories)
+	sort.SliceStable(histories, func(a, b int) bool {
+		return histories[a].Created < histories[b].Created
+	})
+
+	current := StatusPeriod{Status: i.Fields.Status.Name, Start: created}
+	first := true
+	for _, history := range histories {
+		for _, item := range history.Items {
+			if item.Field != "status" {
+				continue
+			}
+
+			changed, err := parseJiraTime(history.Created)
+			if err != nil {
+				return nil, fmt.Errorf("parseJiraTime error: %v\nkey=[%v],history=[%v]", err, i.Key, history.Id)
+			}
+
+			if first {
+				current.Status = item.FromString
+				timeline.Started = changed
+				first = false
+			}
+
+			current.End = changed
+			timeline.Periods = append(timeline.Periods, current)
+			current = StatusPeriod{Status: item.ToString, Start: changed}
+		}
+	}
+
+	current.End = now
+	if !timeline.Resolved.IsZero() && !timeline.Resolved.Before(current.Start) {
+		current.End = timeline.Resolved
+	}
+	timeline.Periods = append(timeline.Periods, current)
+
+	return timeline, nil
+}
+
+func (t *IssueTimeline) LeadTime() (time.Duration, bool) {
+
+	if t.Resolved.IsZero() {
+		return 0, false
+	}
+
+	return t.Resolved.Sub(t.Created), true
+}
+
+func (t *IssueTimeline) CycleTime() (time.Duration, bool) {
+
+	if t.Resolved.IsZero() || t.Started.IsZero() {
+		return 0, false
+	}
+
+	return t.Resolved.Sub(t.Started), true
+}
+
+func (t *IssueTimeline) WorkingLeadTime() (int, bool) {
+
+	if t.Resolved.IsZero() {
+		return 0, false
+	}
+
+	return config.workingSeconds(t.Created, t.Resolved), true
+}
+
+func (t *IssueTimeline) WorkingCycleTime() (int, bool) {
+
+	if t.Resolved.IsZero() || t.Started.IsZero() {
+		return 0, false
+	}
+
+	return config.workingSeconds(t.Started, t.Resolved), true
+}
+
+func (c *Config) workingSeconds(start time.Time, end time.Time) int {
+
+	if !end.After(start) {
+		return 0
+	}
+
+	calendar, err := c.calendar()
+	if err != nil {
+		return 0
+	}
+
+	limit := 60 * 60 * c.HoursPerDay
+	total := 0
+	for day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location()); day.Before(end); day = day.AddDate(0, 0, 1) {
+		if !calendar.IsWorkingDay(day) {
+			continue
+		}
+
+		from := day
+		if from.Before(start) {
+			from = start
+		}
+		to := day.AddDate(0, 0, 1)
+		if to.After(end) {
+			to = end
+		}
+
+		second := int(to.Sub(from).Seconds())
+		if second > limit {
+			second = limit
+		}
+		total += second
+	}
+
+	return total
+}
+
+func (results IssueSearchResults) Timelines() (IssueTimelines, []error) {
+
+	timelines := make(IssueTimelines, 0, 10)
+	timelineErrors := make([]error, 0, 10)
+
+	allIssues := make(Issues, 0, 10)
+	for _, result := range results {
+		allIssues = append(allIssues, result.Issues...)
+	}
+	sort.Sort(allIssues)
+
+	now := config.clock()
+	for _, issue := range allIssues {
+		timeline, err := issue.Timeline(now)
+		if err != nil {
+			timelineErrors = append(timelineErrors, err)
+			continue
+		}
+		timelines = append(timelines, *timeline)
+	}
+
+	return timelines, timelineErrors
+}
+
+func formatDays(d time.Duration) string {
+
+	return fmt.Sprintf("%.2f", d.Hours()/24)
+}
+
+func (results IssueSearchResults) StatusTables() (Tables, error) {
+
+	timelines, timelineErrors := results.Timelines()
+	if len(timelineErrors) > 0 {
+		return nil, fmt.Errorf("Timelines error: %v", timelineErrors)
+	}
+
+	statusTable := newTable("キー", "課題タイプ", "ステータス", "経過日数", "稼働時間")
+	for _, timeline := range timelines {
+		calendar := map[string]time.Duration{}
+		working := map[string]int{}
+		statuses := make([]string, 0, len(timeline.Periods))
+		for _, period := range timeline.Periods {
+			if _, ok := calendar[period.Status]; !ok {
+				statuses = append(statuses, period.Status)
+			}
+			calendar[period.Status] += period.End.Sub(period.Start)
+			working[period.Status] += config.workingSeconds(period.Start, period.End)
+		}
+
+		for _, status := range statuses {
+			statusTable.append([]string{
+				timeline.Key,
+				timeline.Issuetype,
+				status,
+				formatDays(calendar[status]),
+				config.FormatTime(working[status]),
+			})
+		}
+	}
+
+	type average struct {
+		count        int
+		leadTime     time.Duration
+		cycleCount   int
+		cycleTime    time.Duration
+		workingLead  int
+		workingCycle int
+	}
+	averages := map[string]*average{}
+	issuetypes := make([]string, 0, 5)
+
+	leadTimeTable := newTable("キー", "課題タイプ", "作成日時", "解決日時", "リードタイム(日)", "サイクルタイム(日)", "リードタイム(稼働)", "サイクルタイム(稼働)")
+	for _, timeline := range timelines {
+		record := []string{timeline.Key, timeline.Issuetype, timeline.Created.Format(jiraTimeLayout), "", "", "", "", ""}
+
+		leadTime, ok := timeline.LeadTime()
+		if !ok {
+			leadTimeTable.append(record)
+			continue
+		}
+		workingLead, _ := timeline.WorkingLeadTime()
+		record[3] = timeline.Resolved.Format(jiraTimeLayout)
+		record[4] = formatDays(leadTime)
+		record[6] = config.FormatTime(workingLead)
+
+		a, ok := averages[timeline.Issuetype]
+		if !ok {
+			a = &average{}
+			averages[timeline.Issuetype] = a
+			issuetypes = append(issuetypes, timeline.Issuetype)
+		}
+		a.count++
+		a.leadTime += leadTime
+		a.workingLead += workingLead
+
+		if cycleTime, ok := timeline.CycleTime(); ok {
+			workingCycle, _ := timeline.WorkingCycleTime()
+			record[5] = formatDays(cycleTime)
+			record[7] = config.FormatTime(workingCycle)
+
+			a.cycleCount++
+			a.cycleTime += cycleTime
+			a.workingCycle += workingCycle
+		}
+
+		leadTimeTable.append(record)
+	}
+
+	sort.Strings(issuetypes)
+	averageTable := newTable("課題タイプ", "件数", "平均リードタイム(日)", "平均サイクルタイム(日)", "平均リードタイム(稼働)", "平均サイクルタイム(稼働)")
+	for _, issuetype := range issuetypes {
+		a := averages[issuetype]
+		record := []string{
+			issuetype,
+			strconv.Itoa(a.count),
+			formatDays(a.leadTime / time.Duration(a.count)),
+			"",
+			config.FormatTime(a.workingLead / a.count),
+			"",
+		}
+		if a.cycleCount > 0 {
+			record[3] = formatDays(a.cycleTime / time.Duration(a.cycleCount))
+			record[5] = config.FormatTime(a.workingCycle / a.cycleCount)
+		}
+		averageTable.append(record)
+	}
+
+	return Tables{statusTable, leadTimeTable, averageTable}, nil
+}
+
+func getChangelogResult(site Site, key string, queryParams url.Values) (*ChangelogResult, error) {
+
+	changelogURL, err := site.ChangelogURL(key, queryParams)
+	if err != nil {
+		return nil, fmt.Errorf("site.ChangelogURL error: %v\nkey=[%v], queryParams=[%v]", err, key, queryParams)
+	}
+
+	req, err := http.NewRequest("GET", changelogURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequest error: %v\nchangelogURL=[%v]", err, changelogURL)
+	}
+
+	req.Header.Set("Authorization", site.basicAuthorization())
+	req.Header.Set("Accept", "application/json")
+
+	client := httpClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("client.Do error: %v\nreq=[%v]", err, req)
+	}
+	defer resp.Body.Close()
+
+	var result ChangelogResult
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ioutil.ReadAll error: %v\nresp.Body=[%v]", err, resp.Body)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %v\nresponseBody=[%v]", resp.Status, string(responseBody))
+	}
+	if err := json.Unmarshal(responseBody, &result); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error: %v\nresponseBody=[%v]", err, responseBody)
+	}
+
+	return &result, nil
+}
+
+func ChangelogSearch(results IssueSearchResults) []error {
+
+	searchErrors := make([]error, 0, 10)
+
+	for i := range results {
+		for j := range results[i].Issues {
+			issue := &results[i].Issues[j]
+			if len(issue.Changelog.Histories) >= issue.Changelog.Total {
+				continue
+			}
+
+			site, err := config.site(issue.Site)
+			if err != nil {
+				searchErrors = append(searchErrors, fmt.Errorf("config.site error: %v\nkey=[%v]", err, issue.Key))
+				continue
+			}
+
+			histories := make([]History, 0, issue.Changelog.Total)
+			for startAt := 0; startAt < issue.Changelog.Total; {
+				queryParams := url.Values{
+					"startAt":    []string{strconv.Itoa(startAt)},
+					"maxResults": []string{strconv.Itoa(config.MaxResult)},
+				}
+				result, err := getChangelogResult(site, issue.Key, queryParams)
+				if err != nil {
+					searchErrors = append(searchErrors, fmt.Errorf("getChangelogResult error: %v\nkey=[%v], queryParams=[%v]",
+						err, issue.Key, queryParams))
+					break
+				}
+				if len(result.Values) == 0 {
+					break
+				}
+
+				histories = append(histories, result.Values...)
+				startAt += len(result.Values)
+			}
+
+			if len(histories) > len(issue.Changelog.Histories) {
+				issue.Changelog.Histories = histories
+			}
+		}
+	}
+
+	return searchErrors
+}
@@ -0,0 +1,110 @@
+package jira
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIssue_Timeline(t *testing.T) {
+	config.HoursPerDay = 8
+	config.TimeUnit = "hh"
+
+	issue := Issue{
+		Key: "TEST-1",
+		Fields: IssueField{
+			Status:         Status{Name: "Done"},
+			Issuetype:      IssueType{Name: "Task"},
+			Created:        "2020-08-03T09:00:00.000+0900",
+			Resolutiondate: "2020-08-05T09:00:00.000+0900",
+		},
+		Changelog: Changelog{
+			Total: 2,
+			Histories: []History{
+				{
+					Id:      "2",
+					Created: "2020-08-05T09:00:00.000+0900",
+					Items:   []ChangeItem{{Field: "status", FromString: "In Progress", ToString: "Done"}},
+				},
+				{
+					Id:      "1",
+					Created: "2020-08-04T09:00:00.000+0900",
+					Items: []ChangeItem{
+						{Field: "assignee", FromString: "", ToString: "someone"},
+						{Field: "status", FromString: "To Do", ToString: "In Progress"},
+					},
+				},
+			},
+		},
+	}
+
+	now, _ := parseJiraTime("2020-08-31T09:00:00.000+0900")
+	timeline, err := issue.Timeline(now)
+	if err != nil {
+		t.Fatalf("Timeline() error = %v", err)
+	}
+
+	expected := []string{"To Do", "In Progress", "Done"}
+	if len(timeline.Periods) != len(expected) {
+		t.Fatalf("expected=[%v] <> actual=[%v]\n", expected, timeline.Periods)
+	}
+	for i, status := range expected {
+		if timeline.Periods[i].Status != status {
+			t.Errorf("expected=[%v] <> actual=[%v]\n", status, timeline.Periods[i].Status)
+		}
+	}
+
+	if d := timeline.Periods[2].End.Sub(timeline.Periods[2].Start); d != 0 {
+		t.Errorf("expected=[0] <> actual=[%v]\n", d)
+	}
+
+	if leadTime, ok := timeline.LeadTime(); !ok || leadTime != 48*time.Hour {
+		t.Errorf("expected=[48h] <> actual=[%v]\n", leadTime)
+	}
+
+	if cycleTime, ok := timeline.CycleTime(); !ok || cycleTime != 24*time.Hour {
+		t.Errorf("expected=[24h] <> actual=[%v]\n", cycleTime)
+	}
+
+	if working, ok := timeline.WorkingCycleTime(); !ok || working != 16*60*60 {
+		t.Errorf("expected=[%v] <> actual=[%v]\n", 16*60*60, working)
+	}
+}
+
+func TestConfig_workingSeconds(t *testing.T) {
+	c := &Config{HoursPerDay: 8}
+
+	tests := []struct {
+		name  string
+		start string
+		end   string
+		want  int
+	}{
+		{
+			name:  "same day",
+			start: "2020-08-03T09:00:00.000+0900",
+			end:   "2020-08-03T12:00:00.000+0900",
+			want:  3 * 60 * 60,
+		},
+		{
+			name:  "over weekend",
+			start: "2020-08-07T00:00:00.000+0900",
+			end:   "2020-08-10T00:00:00.000+0900",
+			want:  8 * 60 * 60,
+		},
+		{
+			name:  "reversed",
+			start: "2020-08-10T00:00:00.000+0900",
+			end:   "2020-08-07T00:00:00.000+0900",
+			want:  0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, _ := parseJiraTime(tt.start)
+			end, _ := parseJiraTime(tt.end)
+			if got := c.workingSeconds(start, end); got != tt.want {
+				t.Errorf("workingSeconds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,723 @@
+package jira
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	commandReport    = "report"
+	commandSync      = "sync"
+	commandSnapshots = "snapshots"
+	commandDiff      = "diff"
+	commandLock      = "lock"
+	commandUnlock    = "unlock"
+	commandCheck     = "check"
+	defaultCommand   = commandReport
+	defaultDatabase  = "jira-timespent-report.db"
+	storeTimeLayout  = time.RFC3339
+)
+
+const storeSchema = `
+CREATE TABLE IF NOT EXISTS snapshots (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	fetched_at TEXT NOT NULL,
+	target_month TEXT NOT NULL,
+	issue_count INTEGER NOT NULL,
+	worklog_count INTEGER NOT NULL,
+	change_count INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS issues (
+	site TEXT NOT NULL,
+	issue_key TEXT NOT NULL,
+	content TEXT NOT NULL,
+	first_fetched_at TEXT NOT NULL,
+	fetched_at TEXT NOT NULL,
+	PRIMARY KEY (site, issue_key)
+);
+CREATE TABLE IF NOT EXISTS issue_versions (
+	snapshot_id INTEGER NOT NULL,
+	site TEXT NOT NULL,
+	issue_key TEXT NOT NULL,
+	content TEXT NOT NULL,
+	PRIMARY KEY (snapshot_id, site, issue_key)
+);
+CREATE TABLE IF NOT EXISTS snapshot_issues (
+	snapshot_id INTEGER NOT NULL,
+	site TEXT NOT NULL,
+	issue_key TEXT NOT NULL,
+	PRIMARY KEY (snapshot_id, site, issue_key)
+);
+CREATE TABLE IF NOT EXISTS worklogs (
+	site TEXT NOT NULL,
+	worklog_id TEXT NOT NULL,
+	issue_key TEXT NOT NULL,
+	author TEXT NOT NULL,
+	started_millis INTEGER NOT NULL,
+	timespent_seconds INTEGER NOT NULL,
+	content TEXT NOT NULL,
+	deleted INTEGER NOT NULL,
+	first_fetched_at TEXT NOT NULL,
+	fetched_at TEXT NOT NULL,
+	PRIMARY KEY (site, worklog_id)
+);
+CREATE TABLE IF NOT EXISTS worklog_versions (
+	snapshot_id INTEGER NOT NULL,
+	site TEXT NOT NULL,
+	worklog_id TEXT NOT NULL,
+	issue_key TEXT NOT NULL,
+	content TEXT NOT NULL,
+	deleted INTEGER NOT NULL,
+	PRIMARY KEY (snapshot_id, site, worklog_id)
+);
+CREATE TABLE IF NOT EXISTS locks (
+	target_month TEXT PRIMARY KEY,
+	locked_at TEXT NOT NULL,
+	checksum TEXT NOT NULL,
+	content TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	schedule TEXT NOT NULL,
+	started_at TEXT NOT NULL,
+	finished_at TEXT NOT NULL,
+	status TEXT NOT NULL,
+	destination TEXT NOT NULL,
+	message TEXT NOT NULL
+);
+`
+
+var storeFields = []string{
+	"summary",
+	"status",
+	"timespent",
+	"timeoriginalestimate",
+	"aggregatetimespent",
+	"aggregatetimeoriginalestimate",
+	"issuetype",
+	"created",
+	"resolutiondate",
+	"project",
+	"parent",
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+type Snapshot struct {
+	Id          int
+	FetchedAt   time.Time
+	TargetMonth string
+	Issues      int
+	Worklogs    int
+	Changes     int
+}
+
+func Command() string {
+
+	if len(config.Command) == 0 {
+		return defaultCommand
+	}
+
+	return config.Command
+}
+
+func (c *Config) validateCommand() error {
+
+	switch Command() {
+	case commandReport:
+		return nil
+	case commandSync:
+		if c.FromDb {
+			return fmt.Errorf("-from-db can not be used with %v", commandSync)
+		}
+		return nil
+	case commandSnapshots, commandDiff, commandLock, commandUnlock, commandCheck:
+		return nil
+	}
+
+	return fmt.Errorf("unknown command: %v", c.Command)
+}
+
+func (c *Config) offline() bool {
+
+	switch Command() {
+	case commandReport, commandLock, commandCheck:
+		return c.FromDb
+	case commandSnapshots, commandDiff, commandUnlock:
+		return true
+	}
+
+	return false
+}
+
+func OpenStore(name string) (*Store, error) {
+
+	if len(name) == 0 {
+		return nil, fmt.Errorf("empty database name")
+	}
+
+	db, err := sql.Open("sqlite", name)
+	if err != nil {
+		return nil, fmt.Errorf("sql.Open error: %v\nname=[%v]", err, name)
+	}
+
+	if _, err := db.Exec(storeSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("db.Exec error: %v\nname=[%v]", err, name)
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+
+	return s.db.Close()
+}
+
+func (s *Store) Sync(issues IssueSearchResults, worklogs WorklogResults, unfetched Issues, unfetchedWorklogs WorklogResults, fetchedAt time.Time) (*Snapshot, error) {
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("db.Begin error: %v", err)
+	}
+	defer tx.Rollback()
+
+	snapshot := &Snapshot{FetchedAt: fetchedAt}
+	if target, err := config.TargetMonth(); err == nil {
+		snapshot.TargetMonth = target.Format("2006-01")
+	}
+
+	result, err := tx.Exec("INSERT INTO snapshots (fetched_at, target_month, issue_count, worklog_count, change_count) VALUES (?, ?, 0, 0, 0)",
+		fetchedAt.Format(storeTimeLayout), snapshot.TargetMonth)
+	if err != nil {
+		return nil, fmt.Errorf("tx.Exec error: %v", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("result.LastInsertId error: %v", err)
+	}
+	snapshot.Id = int(id)
+
+	fetchedIssues := make(Issues, 0, 10)
+	for _, issue := range issues.issueMap() {
+		if _, err := tx.Exec("INSERT INTO snapshot_issues (snapshot_id, site, issue_key) VALUES (?, ?, ?)",
+			snapshot.Id, issue.Site, issue.Key); err != nil {
+			return nil, fmt.Errorf("tx.Exec error: %v\nkey=[%v]", err, issue.Key)
+		}
+		changed, err := snapshot.putIssue(tx, issue)
+		if err != nil {
+			return nil, err
+		}
+		fetchedIssues = append(fetchedIssues, issue)
+		snapshot.Issues++
+		if changed {
+			snapshot.Changes++
+		}
+	}
+
+	fetched := map[string]map[string]bool{}
+	for _, result := range worklogs {
+		for _, worklog := range result.Worklogs {
+			changed, err := snapshot.putWorklog(tx, worklog)
+			if err != nil {
+				return nil, err
+			}
+			if fetched[worklog.siteKey()] == nil {
+				fetched[worklog.siteKey()] = map[string]bool{}
+			}
+			fetched[worklog.siteKey()][worklog.Id] = true
+			snapshot.Worklogs++
+			if changed {
+				snapshot.Changes++
+			}
+		}
+	}
+
+	for _, result := range unfetchedWorklogs {
+		for _, worklog := range result.Worklogs {
+			changed, err := snapshot.putWorklog(tx, worklog)
+			if err != nil {
+				return nil, err
+			}
+			if fetched[worklog.siteKey()] == nil {
+				fetched[worklog.siteKey()] = map[string]bool{}
+			}
+			fetched[worklog.siteKey()][worklog.Id] = true
+			if changed {
+				snapshot.Changes++
+			}
+		}
+	}
+
+	if config.collectWorklog() {
+		for _, issue := range append(fetchedIssues, unfetched...) {
+			deleted, err := snapshot.deleteWorklogs(tx, issue.Site, issue.Key, fetched[issue.siteKey()])
+			if err != nil {
+				return nil, err
+			}
+			snapshot.Changes += deleted
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE snapshots SET issue_count = ?, worklog_count = ?, change_count = ? WHERE id = ?",
+		snapshot.Issues, snapshot.Worklogs, snapshot.Changes, snapshot.Id); err != nil {
+		return nil, fmt.Errorf("tx.Exec error: %v\nsnapshot=[%v]", err, snapshot.Id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("tx.Commit error: %v", err)
+	}
+
+	return snapshot, nil
+}
+
+func (s *Snapshot) putIssue(tx *sql.Tx, issue Issue) (bool, error) {
+
+	content, err := json.Marshal(issue)
+	if err != nil {
+		return false, fmt.Errorf("json.Marshal error: %v\nkey=[%v]", err, issue.Key)
+	}
+	fetchedAt := s.FetchedAt.Format(storeTimeLayout)
+
+	var current string
+	err = tx.QueryRow("SELECT content FROM issues WHERE site = ? AND issue_key = ?", issue.Site, issue.Key).Scan(&current)
+	switch {
+	case err == sql.ErrNoRows:
+		_, err = tx.Exec("INSERT INTO issues (site, issue_key, content, first_fetched_at, fetched_at) VALUES (?, ?, ?, ?, ?)",
+			issue.Site, issue.Key, string(content), fetchedAt, fetchedAt)
+	case err != nil:
+		return false, fmt.Errorf("tx.QueryRow error: %v\nkey=[%v]", err, issue.Key)
+	case current == string(content):
+		_, err = tx.Exec("UPDATE issues SET fetched_at = ? WHERE site = ? AND issue_key = ?", fetchedAt, issue.Site, issue.Key)
+		if err != nil {
+			return false, fmt.Errorf("tx.Exec error: %v\nkey=[%v]", err, issue.Key)
+		}
+		return false, nil
+	default:
+		_, err = tx.Exec("UPDATE issues SET content = ?, fetched_at = ? WHERE site = ? AND issue_key = ?",
+			string(content), fetchedAt, issue.Site, issue.Key)
+	}
+	if err != nil {
+		return false, fmt.Errorf("tx.Exec error: %v\nkey=[%v]", err, issue.Key)
+	}
+
+	if _, err := tx.Exec("INSERT INTO issue_versions (snapshot_id, site, issue_key, content) VALUES (?, ?, ?, ?)",
+		s.Id, issue.Site, issue.Key, string(content)); err != nil {
+		return false, fmt.Errorf("tx.Exec error: %v\nkey=[%v]", err, issue.Key)
+	}
+
+	return true, nil
+}
+
+func (s *Snapshot) putWorklog(tx *sql.Tx, worklog WorklogField) (bool, error) {
+
+	if len(worklog.Id) == 0 {
+		return false, fmt.Errorf("empty worklog id\nkey=[%v],started=[%v]", worklog.Key, worklog.Started)
+	}
+
+	started, err := worklog.StartedTime()
+	if err != nil {
+		return false, fmt.Errorf("worklog.StartedTime error: %v\nkey=[%v],started=[%v]", err, worklog.Key, worklog.Started)
+	}
+
+	content, err := json.Marshal(worklog)
+	if err != nil {
+		return false, fmt.Errorf("json.Marshal error: %v\nkey=[%v],id=[%v]", err, worklog.Key, worklog.Id)
+	}
+	fetchedAt := s.FetchedAt.Format(storeTimeLayout)
+
+	var current string
+	var deleted bool
+	err = tx.QueryRow("SELECT content, deleted FROM worklogs WHERE site = ? AND worklog_id = ?", worklog.Site, worklog.Id).Scan(&current, &deleted)
+	switch {
+	case err == sql.ErrNoRows:
+		_, err = tx.Exec("INSERT INTO worklogs (site, worklog_id, issue_key, author, started_millis, timespent_seconds, content, deleted, first_fetched_at, fetched_at) VALUES (?, ?, ?, ?, ?, ?, ?, 0, ?, ?)",
+			worklog.Site, worklog.Id, worklog.Key, worklog.authorKey(), started.UnixNano()/int64(time.Millisecond), worklog.Timespentseconds, string(content), fetchedAt, fetchedAt)
+	case err != nil:
+		return false, fmt.Errorf("tx.QueryRow error: %v\nkey=[%v],id=[%v]", err, worklog.Key, worklog.Id)
+	case current == string(content) && !deleted:
+		_, err = tx.Exec("UPDATE worklogs SET fetched_at = ? WHERE site = ? AND worklog_id = ?", fetchedAt, worklog.Site, worklog.Id)
+		if err != nil {
+			return false, fmt.Errorf("tx.Exec error: %v\nkey=[%v],id=[%v]", err, worklog.Key, worklog.Id)
+		}
+		return false, nil
+	default:
+		_, err = tx.Exec("UPDATE worklogs SET issue_key = ?, author = ?, started_millis = ?, timespent_seconds = ?, content = ?, deleted = 0, fetched_at = ? WHERE site = ? AND worklog_id = ?",
+			worklog.Key, worklog.authorKey(), started.UnixNano()/int64(time.Millisecond), worklog.Timespentseconds, string(content), fetchedAt, worklog.Site, worklog.Id)
+	}
+	if err != nil {
+		return false, fmt.Errorf("tx.Exec error: %v\nkey=[%v],id=[%v]", err, worklog.Key, worklog.Id)
+	}
+
+	if _, err := tx.Exec("INSERT INTO worklog_versions (snapshot_id, site, worklog_id, issue_key, content, deleted) VALUES (?, ?, ?, ?, ?, 0)",
+		s.Id, worklog.Site, worklog.Id, worklog.Key, string(content)); err != nil {
+		return false, fmt.Errorf("tx.Exec error: %v\nkey=[%v],id=[%v]", err, worklog.Key, worklog.Id)
+	}
+
+	return true, nil
+}
+
+func (s *Snapshot) deleteWorklogs(tx *sql.Tx, site string, key string, fetched map[string]bool) (int, error) {
+
+	startedAfter, _ := strconv.ParseInt(config.StartedAfter(), 10, 64)
+
+	rows, err := tx.Query("SELECT worklog_id, content FROM worklogs WHERE site = ? AND issue_key = ? AND deleted = 0 AND started_millis >= ?",
+		site, key, startedAfter)
+	if err != nil {
+		return 0, fmt.Errorf("tx.Query error: %v\nkey=[%v]", err, key)
+	}
+
+	contents := map[string]string{}
+	for rows.Next() {
+		var id, content string
+		if err := rows.Scan(&id, &content); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("rows.Scan error: %v\nkey=[%v]", err, key)
+		}
+		if !fetched[id] {
+			contents[id] = content
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("rows.Err error: %v\nkey=[%v]", err, key)
+	}
+
+	fetchedAt := s.FetchedAt.Format(storeTimeLayout)
+	for id, content := range contents {
+		if _, err := tx.Exec("UPDATE worklogs SET deleted = 1, fetched_at = ? WHERE site = ? AND worklog_id = ?", fetchedAt, site, id); err != nil {
+			return 0, fmt.Errorf("tx.Exec error: %v\nkey=[%v],id=[%v]", err, key, id)
+		}
+		if _, err := tx.Exec("INSERT INTO worklog_versions (snapshot_id, site, worklog_id, issue_key, content, deleted) VALUES (?, ?, ?, ?, ?, 1)",
+			s.Id, site, id, key, content); err != nil {
+			return 0, fmt.Errorf("tx.Exec error: %v\nkey=[%v],id=[%v]", err, key, id)
+		}
+	}
+
+	return len(contents), nil
+}
+
+func (s *Store) monthIssues() (Issues, error) {
+
+	targetMonth, err := config.targetMonthText()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query("SELECT DISTINCT si.site, si.issue_key FROM snapshot_issues si JOIN snapshots s ON s.id = si.snapshot_id WHERE s.target_month = ? ORDER BY si.site, si.issue_key", targetMonth)
+	if err != nil {
+		return nil, fmt.Errorf("db.Query error: %v\ntargetMonth=[%v]", err, targetMonth)
+	}
+	defer rows.Close()
+
+	issues := make(Issues, 0, 10)
+	for rows.Next() {
+		var issue Issue
+		if err := rows.Scan(&issue.Site, &issue.Key); err != nil {
+			return nil, fmt.Errorf("rows.Scan error: %v", err)
+		}
+		issues = append(issues, issue)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err error: %v", err)
+	}
+
+	return issues, nil
+}
+
+func (s *Store) Snapshots() ([]Snapshot, error) {
+
+	rows, err := s.db.Query("SELECT id, fetched_at, target_month, issue_count, worklog_count, change_count FROM snapshots ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("db.Query error: %v", err)
+	}
+	defer rows.Close()
+
+	snapshots := make([]Snapshot, 0, 10)
+	for rows.Next() {
+		var snapshot Snapshot
+		var fetchedAt string
+		if err := rows.Scan(&snapshot.Id, &fetchedAt, &snapshot.TargetMonth, &snapshot.Issues, &snapshot.Worklogs, &snapshot.Changes); err != nil {
+			return nil, fmt.Errorf("rows.Scan error: %v", err)
+		}
+		if snapshot.FetchedAt, err = time.Parse(storeTimeLayout, fetchedAt); err != nil {
+			return nil, fmt.Errorf("time.Parse error: %v\nfetchedAt=[%v]", err, fetchedAt)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err error: %v", err)
+	}
+
+	return snapshots, nil
+}
+
+func (s *Store) snapshotId(id int) (int, error) {
+
+	var latest sql.NullInt64
+	if err := s.db.QueryRow("SELECT MAX(id) FROM snapshots").Scan(&latest); err != nil {
+		return 0, fmt.Errorf("db.QueryRow error: %v", err)
+	}
+	if !latest.Valid {
+		return 0, fmt.Errorf("no snapshot")
+	}
+
+	if id <= 0 {
+		return int(latest.Int64), nil
+	}
+	if id > int(latest.Int64) {
+		return 0, fmt.Errorf("unknown snapshot: %v", id)
+	}
+
+	return id, nil
+}
+
+func (s *Store) monthSnapshotId(id int) (int, error) {
+
+	snapshotId, err := s.snapshotId(id)
+	if err != nil {
+		return 0, err
+	}
+
+	targetMonth, err := config.targetMonthText()
+	if err != nil {
+		return 0, err
+	}
+
+	var monthId sql.NullInt64
+	if err := s.db.QueryRow("SELECT MAX(id) FROM snapshots WHERE target_month = ? AND id <= ?", targetMonth, snapshotId).Scan(&monthId); err != nil {
+		return 0, fmt.Errorf("db.QueryRow error: %v\ntargetMonth=[%v]", err, targetMonth)
+	}
+	if !monthId.Valid {
+		return 0, fmt.Errorf("no snapshot of target month: %v\nsnapshot=[%v]", targetMonth, snapshotId)
+	}
+
+	return int(monthId.Int64), nil
+}
+
+func (s *Store) Load(id int) (IssueSearchResults, WorklogResults, error) {
+
+	snapshotId, err := s.monthSnapshotId(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	issueRows, err := s.db.Query(`SELECT v.content FROM issue_versions v
+JOIN snapshot_issues i ON i.site = v.site AND i.issue_key = v.issue_key AND i.snapshot_id = ?
+WHERE v.snapshot_id = (SELECT MAX(w.snapshot_id) FROM issue_versions w WHERE w.site = v.site AND w.issue_key = v.issue_key AND w.snapshot_id <= ?)
+ORDER BY v.site, v.issue_key`, snapshotId, snapshotId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("db.Query error: %v\nsnapshot=[%v]", err, snapshotId)
+	}
+	defer issueRows.Close()
+
+	issues := make(Issues, 0, 10)
+	for issueRows.Next() {
+		var content string
+		if err := issueRows.Scan(&content); err != nil {
+			return nil, nil, fmt.Errorf("rows.Scan error: %v\nsnapshot=[%v]", err, snapshotId)
+		}
+		var issue Issue
+		if err := json.Unmarshal([]byte(content), &issue); err != nil {
+			return nil, nil, fmt.Errorf("json.Unmarshal error: %v\ncontent=[%v]", err, content)
+		}
+		issues = append(issues, issue)
+	}
+	if err := issueRows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("rows.Err error: %v\nsnapshot=[%v]", err, snapshotId)
+	}
+
+	worklogRows, err := s.db.Query(`SELECT v.content FROM worklog_versions v
+WHERE v.snapshot_id = (SELECT MAX(w.snapshot_id) FROM worklog_versions w WHERE w.site = v.site AND w.worklog_id = v.worklog_id AND w.snapshot_id <= ?)
+AND v.deleted = 0`, snapshotId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("db.Query error: %v\nsnapshot=[%v]", err, snapshotId)
+	}
+	defer worklogRows.Close()
+
+	worklogMap := map[string]Worklogs{}
+	for worklogRows.Next() {
+		var content string
+		if err := worklogRows.Scan(&content); err != nil {
+			return nil, nil, fmt.Errorf("rows.Scan error: %v\nsnapshot=[%v]", err, snapshotId)
+		}
+		var worklog WorklogField
+		if err := json.Unmarshal([]byte(content), &worklog); err != nil {
+			return nil, nil, fmt.Errorf("json.Unmarshal error: %v\ncontent=[%v]", err, content)
+		}
+		started, err := worklog.StartedTime()
+		if err != nil {
+			return nil, nil, fmt.Errorf("worklog.StartedTime error: %v\nkey=[%v],started=[%v]", err, worklog.Key, worklog.Started)
+		}
+		if !config.inTargetMonth(started) {
+			continue
+		}
+		worklogMap[worklog.siteKey()] = append(worklogMap[worklog.siteKey()], worklog)
+	}
+	if err := worklogRows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("rows.Err error: %v\nsnapshot=[%v]", err, snapshotId)
+	}
+
+	issueResults := IssueSearchResults{}
+	if len(issues) > 0 {
+		issueResults = append(issueResults, IssueSearchResult{Total: len(issues), MaxResults: len(issues), Issues: issues})
+	}
+
+	worklogResults := make(WorklogResults, 0, len(worklogMap))
+	for _, issue := range issues {
+		worklogs, ok := worklogMap[issue.siteKey()]
+		if !ok {
+			continue
+		}
+		sort.Sort(worklogs)
+		worklogResults = append(worklogResults, WorklogResult{Total: len(worklogs), MaxResults: len(worklogs), Worklogs: worklogs})
+	}
+
+	return issueResults, worklogResults, nil
+}
+
+func LoadFromStore() (IssueSearchResults, WorklogResults, []error) {
+
+	store, err := OpenStore(config.Database)
+	if err != nil {
+		return IssueSearchResults{}, WorklogResults{}, []error{fmt.Errorf("OpenStore error: %v", err)}
+	}
+	defer store.Close()
+
+	issues, worklogs, err := store.Load(config.Snapshot)
+	if err != nil {
+		return IssueSearchResults{}, WorklogResults{}, []error{fmt.Errorf("store.Load error: %v", err)}
+	}
+
+	if !config.collectWorklog() {
+		var nothing WorklogResults
+		return issues, nothing, nil
+	}
+
+	return issues, worklogs, nil
+}
+
+func RenderSnapshotsCsv(w io.Writer, snapshots []Snapshot) error {
+
+	writer := csv.NewWriter(w)
+	records := make([][]string, 0, 10)
+	records = append(records, []string{"スナップショットID", "取得日時", "対象年月", "課題数", "作業ログ数", "変更数"})
+	for _, snapshot := range snapshots {
+		records = append(records, []string{
+			strconv.Itoa(snapshot.Id),
+			snapshot.FetchedAt.Format(storeTimeLayout),
+			snapshot.TargetMonth,
+			strconv.Itoa(snapshot.Issues),
+			strconv.Itoa(snapshot.Worklogs),
+			strconv.Itoa(snapshot.Changes),
+		})
+	}
+
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("writer.Write error: %v\nrecord=[%v]\n", err, record)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("writer.Error error: %v\n", err)
+	}
+
+	return nil
+}
+
+func Sync(w io.Writer) []error {
+
+	issues, worklogs, syncErrors := Search()
+	if len(syncErrors) > 0 {
+		return syncErrors
+	}
+
+	store, err := OpenStore(config.Database)
+	if err != nil {
+		return append(syncErrors, fmt.Errorf("OpenStore error: %v", err))
+	}
+	defer store.Close()
+
+	var unfetched Issues
+	var unfetchedWorklogs WorklogResults
+	if config.collectWorklog() {
+		stored, err := store.monthIssues()
+		if err != nil {
+			return append(syncErrors, fmt.Errorf("store.monthIssues error: %v", err))
+		}
+		unfetched = unfetchedIssues(stored, issues)
+		worklogs, unfetchedErrors := issueWorklogs(unfetched)
+		if len(unfetchedErrors) > 0 {
+			return append(syncErrors, unfetchedErrors...)
+		}
+		if resolveErrors := ResolveAuthors(worklogs); len(resolveErrors) > 0 {
+			return append(syncErrors, resolveErrors...)
+		}
+		if err := ReconcileAuthors(worklogs); err != nil {
+			return append(syncErrors, err)
+		}
+		unfetchedWorklogs = worklogs
+	}
+
+	snapshot, err := store.Sync(issues, worklogs, unfetched, unfetchedWorklogs, config.clock())
+	if err != nil {
+		return append(syncErrors, fmt.Errorf("store.Sync error: %v", err))
+	}
+
+	if err := RenderSnapshotsCsv(w, []Snapshot{*snapshot}); err != nil {
+		syncErrors = append(syncErrors, err)
+	}
+
+	targetMonth, err := config.targetMonthText()
+	if err != nil {
+		return syncErrors
+	}
+	lock, err := store.lock(targetMonth)
+	if err != nil {
+		return append(syncErrors, fmt.Errorf("store.lock error: %v", err))
+	}
+	if lock == nil {
+		return syncErrors
+	}
+
+	checks := []LockCheck{*checkLock(*lock, issues, worklogs)}
+	if err := RenderLockCsv(w, checks); err != nil {
+		syncErrors = append(syncErrors, err)
+	}
+
+	return append(syncErrors, lateChangeErrors(checks)...)
+}
+
+func Snapshots(w io.Writer) []error {
+
+	store, err := OpenStore(config.Database)
+	if err != nil {
+		return []error{fmt.Errorf("OpenStore error: %v", err)}
+	}
+	defer store.Close()
+
+	snapshots, err := store.Snapshots()
+	if err != nil {
+		return []error{fmt.Errorf("store.Snapshots error: %v", err)}
+	}
+
+	if err := RenderSnapshotsCsv(w, snapshots); err != nil {
+		return []error{err}
+	}
+
+	return nil
+}
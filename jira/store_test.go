@@ -0,0 +1,185 @@
+package jira
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStore_Sync(t *testing.T) {
+	saved := *config
+	defer func() { *config = saved }()
+	config.TargetYearMonth = "2020-08"
+	config.Sites = ""
+	config.Command = commandSync
+
+	store, err := OpenStore(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	defer store.Close()
+
+	issues := IssueSearchResults{{Issues: Issues{{Id: "10001", Key: "DEMO-1", Fields: IssueField{Summary: "ログイン画面の作成"}}}}}
+	worklog := func(id string, started string, second int) WorklogField {
+		return WorklogField{Key: "DEMO-1", Id: id, Author: User{AccountId: "5b10a2844c20165700ede21g"}, Started: started, Timespentseconds: second}
+	}
+	first := WorklogResults{{Worklogs: Worklogs{
+		worklog("30001", "2020-08-03T09:00:00.000+0900", 28800),
+		worklog("30002", "2020-08-04T09:00:00.000+0900", 14400),
+		worklog("30003", "2020-09-01T09:00:00.000+0900", 3600),
+	}}}
+	second := WorklogResults{{Worklogs: Worklogs{
+		worklog("30001", "2020-08-03T09:00:00.000+0900", 21600),
+	}}}
+
+	tests := []struct {
+		name     string
+		worklogs WorklogResults
+		changes  int
+	}{
+		{name: "first", worklogs: first, changes: 4},
+		{name: "unchanged", worklogs: first, changes: 0},
+		{name: "updated and deleted", worklogs: second, changes: 3},
+		{name: "all deleted", worklogs: WorklogResults{}, changes: 1},
+	}
+	fetchedAt := time.Date(2020, 9, 1, 9, 0, 0, 0, time.UTC)
+	for i, tt := range tests {
+		snapshot, err := store.Sync(issues, tt.worklogs, nil, nil, fetchedAt.AddDate(0, 0, i))
+		if err != nil {
+			t.Fatalf("%v: store.Sync() error = %v", tt.name, err)
+		}
+		if snapshot.Id != i+1 || snapshot.Changes != tt.changes || snapshot.TargetMonth != "2020-08" {
+			t.Errorf("%v: expected=[%v,%v] <> actual[%v,%v]\n", tt.name, i+1, tt.changes, snapshot.Id, snapshot.Changes)
+		}
+	}
+
+	for id, expected := range map[int]Worklogs{1: first[0].Worklogs[:2], 2: first[0].Worklogs[:2], 3: second[0].Worklogs, 4: {}} {
+		loadedIssues, loadedWorklogs, err := store.Load(id)
+		if err != nil {
+			t.Fatalf("store.Load(%v) error = %v", id, err)
+		}
+		if !reflect.DeepEqual(loadedIssues[0].Issues, issues[0].Issues) {
+			t.Errorf("expected=[%v] <> actual[%v]\n", issues[0].Issues, loadedIssues[0].Issues)
+		}
+		actual := Worklogs{}
+		for _, result := range loadedWorklogs {
+			actual = append(actual, result.Worklogs...)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("snapshot=%v: expected=[%v] <> actual[%v]\n", id, expected, actual)
+		}
+	}
+
+	config.TargetYearMonth = "2020-09"
+	if _, _, err := store.Load(0); err == nil {
+		t.Errorf("store.Load(0) error = nil, want error")
+	}
+	other := IssueSearchResults{{Issues: Issues{{Id: "10002", Key: "DEMO-2", Fields: IssueField{Summary: "ログアウト"}}}}}
+	if _, err := store.Sync(other, WorklogResults{}, nil, nil, fetchedAt.AddDate(0, 0, 4)); err != nil {
+		t.Fatalf("store.Sync() error = %v", err)
+	}
+	config.TargetYearMonth = "2020-08"
+	loadedIssues, _, err := store.Load(0)
+	if err != nil {
+		t.Fatalf("store.Load(0) error = %v", err)
+	}
+	if !reflect.DeepEqual(loadedIssues[0].Issues, issues[0].Issues) {
+		t.Errorf("expected=[%v] <> actual[%v]\n", issues[0].Issues, loadedIssues[0].Issues)
+	}
+
+	if _, _, err := store.Load(6); err == nil {
+		t.Errorf("store.Load(6) error = nil, want error")
+	}
+
+	snapshots, err := store.Snapshots()
+	if err != nil {
+		t.Fatalf("store.Snapshots() error = %v", err)
+	}
+	if len(snapshots) != 5 || !snapshots[2].FetchedAt.Equal(fetchedAt.AddDate(0, 0, 2)) || snapshots[0].Worklogs != 3 || snapshots[4].TargetMonth != "2020-09" {
+		t.Errorf("expected=[5 snapshots] <> actual[%v]\n", snapshots)
+	}
+}
+
+func TestSyncAndReportFromDb(t *testing.T) {
+	setupFakeJira(t)
+	config.Worklog = true
+	expected := searchAndReport(t)
+
+	config.Database = filepath.Join(t.TempDir(), "store.db")
+	config.Command = commandSync
+	if syncErrors := Sync(ioutil.Discard); len(syncErrors) > 0 {
+		t.Fatalf("Sync() errors = %v", syncErrors)
+	}
+
+	config.Command = commandReport
+	config.FromDb = true
+	if actual := searchAndReport(t); actual != expected {
+		t.Errorf("expected=[%v] <> actual[%v]\n", expected, actual)
+	}
+}
+
+func TestSync_SearchErrors(t *testing.T) {
+	server := setupFakeJira(t)
+	config.Database = filepath.Join(t.TempDir(), "store.db")
+	config.Command = commandSync
+	server.FailNext(100)
+
+	if syncErrors := Sync(ioutil.Discard); len(syncErrors) == 0 {
+		t.Fatalf("Sync() errors = nil, want errors")
+	}
+
+	store, err := OpenStore(config.Database)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	defer store.Close()
+	snapshots, err := store.Snapshots()
+	if err != nil {
+		t.Fatalf("store.Snapshots() error = %v", err)
+	}
+	if len(snapshots) != 0 {
+		t.Errorf("expected=[%v] <> actual[%v]\n", 0, snapshots)
+	}
+}
+
+func TestSync_UnfetchedIssues(t *testing.T) {
+	server := setupFakeJira(t)
+	config.Database = filepath.Join(t.TempDir(), "store.db")
+	config.Command = commandSync
+
+	store, err := OpenStore(config.Database)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	defer store.Close()
+	issues := IssueSearchResults{{Issues: Issues{{Key: "OPS-1"}}}}
+	worklogs := WorklogResults{{Worklogs: Worklogs{{Key: "OPS-1", Id: "99999", Started: "2020-08-10T09:00:00.000+0900", Timespentseconds: 3600}}}}
+	if _, err := store.Sync(issues, worklogs, nil, nil, time.Date(2020, 9, 1, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("store.Sync() error = %v", err)
+	}
+
+	if syncErrors := Sync(ioutil.Discard); len(syncErrors) > 0 {
+		t.Fatalf("Sync() errors = %v", syncErrors)
+	}
+	if actual := server.Requests("issue/worklog"); actual != 4 {
+		t.Errorf("expected=[%v] <> actual[%v]\n", 4, actual)
+	}
+
+	var deleted bool
+	if err := store.db.QueryRow("SELECT deleted FROM worklogs WHERE worklog_id = ?", "99999").Scan(&deleted); err != nil {
+		t.Fatalf("db.QueryRow() error = %v", err)
+	}
+	if !deleted {
+		t.Errorf("expected=[%v] <> actual[%v]\n", true, deleted)
+	}
+
+	snapshots, err := store.Snapshots()
+	if err != nil {
+		t.Fatalf("store.Snapshots() error = %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[1].Issues != 3 || snapshots[1].Changes != 9 {
+		t.Errorf("expected=[2 snapshots] <> actual[%v]\n", snapshots)
+	}
+}
@@ -0,0 +1,169 @@
+package jira
+
+import (
+	"fmt"
+	"log"
+	"sort"
+)
+
+const maxTopIssues = 5
+
+type ReportSummary struct {
+	Name           string
+	Report         string
+	TargetMonth    string
+	Issues         int
+	Worklogs       int
+	Total          string
+	TotalHours     string
+	Authors        []AuthorSummary
+	TopIssues      []IssueSummary
+	MissingAuthors []MissingAuthor
+	Link           string
+}
+
+type AuthorSummary struct {
+	Displayname  string
+	Emailaddress string
+	Timespent    string
+}
+
+type IssueSummary struct {
+	Key       string
+	Summary   string
+	Timespent string
+}
+
+type MissingAuthor struct {
+	Displayname  string
+	Emailaddress string
+	Days         int
+}
+
+func reportName() string {
+
+	if len(config.Report) == 0 {
+		return defaultReport
+	}
+
+	return config.Report
+}
+
+func newReportSummary(name string, issues IssueSearchResults, worklogs WorklogResults) *ReportSummary {
+
+	summary := &ReportSummary{Name: name, Report: config.Report}
+	if targetMonth, err := config.targetMonthText(); err == nil {
+		summary.TargetMonth = targetMonth
+	}
+
+	var total TimeTotal
+	issueTotals := map[string]*TimeTotal{}
+	issueSummaries := map[string]string{}
+	for _, result := range issues {
+		summary.Issues += len(result.Issues)
+		for _, issue := range result.Issues {
+			issueSummaries[issue.siteKey()] = issue.Fields.Summary
+			if worklogs == nil {
+				total.Add(issue.Fields.Timespent)
+				issueTotals[issue.siteKey()] = &TimeTotal{}
+				issueTotals[issue.siteKey()].Add(issue.Fields.Timespent)
+			}
+		}
+	}
+
+	authors := map[string]*TimeTotal{}
+	users := map[string]User{}
+	for _, result := range worklogs {
+		for _, worklog := range result.Worklogs {
+			summary.Worklogs++
+			total.Add(worklog.Timespentseconds)
+
+			key := worklog.authorKey()
+			if _, ok := authors[key]; !ok {
+				authors[key] = &TimeTotal{}
+				users[key] = worklog.Author
+			}
+			authors[key].Add(worklog.Timespentseconds)
+
+			if _, ok := issueTotals[worklog.siteKey()]; !ok {
+				issueTotals[worklog.siteKey()] = &TimeTotal{}
+			}
+			issueTotals[worklog.siteKey()].Add(worklog.Timespentseconds)
+		}
+	}
+	summary.Total = total.String()
+	summary.TotalHours = fmt.Sprintf("%.2f", float64(total.Seconds())/60/60)
+
+	for key, author := range authors {
+		summary.Authors = append(summary.Authors, AuthorSummary{
+			Displayname:  users[key].Displayname,
+			Emailaddress: users[key].Emailaddress,
+			Timespent:    author.String(),
+		})
+	}
+	sort.Slice(summary.Authors, func(i, j int) bool {
+		return summary.Authors[i].Emailaddress < summary.Authors[j].Emailaddress
+	})
+
+	keys := make([]string, 0, len(issueTotals))
+	for key, issueTotal := range issueTotals {
+		if issueTotal.Seconds() > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := issueTotals[keys[i]].Seconds(), issueTotals[keys[j]].Seconds()
+		if a == b {
+			return keys[i] < keys[j]
+		}
+		return a > b
+	})
+	if len(keys) > maxTopIssues {
+		keys = keys[:maxTopIssues]
+	}
+	for _, key := range keys {
+		summary.TopIssues = append(summary.TopIssues, IssueSummary{
+			Key:       key,
+			Summary:   issueSummaries[key],
+			Timespent: issueTotals[key].String(),
+		})
+	}
+
+	if worklogs != nil {
+		missingAuthors, err := missingAuthors(worklogs)
+		if err != nil {
+			log.Printf("missingAuthors error: %v\n", err)
+		}
+		summary.MissingAuthors = missingAuthors
+	}
+
+	return summary
+}
+
+func missingAuthors(worklogs WorklogResults) ([]MissingAuthor, error) {
+
+	roster, err := config.roster()
+	if err != nil {
+		return nil, fmt.Errorf("config.roster error: %v", err)
+	}
+
+	compliance, err := worklogs.inTargetMonth().Compliance(roster)
+	if err != nil {
+		return nil, fmt.Errorf("Compliance error: %v", err)
+	}
+
+	missing := make([]MissingAuthor, 0, len(compliance))
+	for _, author := range compliance {
+		days := 0
+		for _, day := range author.Days {
+			if day.Kind == complianceMissing {
+				days++
+			}
+		}
+		if days > 0 {
+			missing = append(missing, MissingAuthor{Displayname: author.Displayname, Emailaddress: author.Emailaddress, Days: days})
+		}
+	}
+
+	return missing, nil
+}
@@ -0,0 +1,46 @@
+package jira
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+type Table struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+type Tables []Table
+
+func newTable(columns ...string) Table {
+
+	return Table{Columns: columns, Rows: make([][]string, 0, 10)}
+}
+
+func (t *Table) append(row []string) {
+
+	t.Rows = append(t.Rows, row)
+}
+
+func (tables Tables) RenderCsv(w io.Writer) error {
+
+	writer := csv.NewWriter(w)
+	for _, table := range tables {
+		if err := writer.Write(table.Columns); err != nil {
+			return fmt.Errorf("writer.Write error: %v\ncolumns=[%v]\n", err, table.Columns)
+		}
+		for _, record := range table.Rows {
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("writer.Write error: %v\nrecord=[%v]\n", err, record)
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("writer.Error error: %v\n", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,335 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	allocationFirst   = "first"
+	allocationSplit   = "split"
+	allocationAll     = "all"
+	defaultAllocation = allocationFirst
+	unassignedTeam    = "(未所属)"
+)
+
+type Team struct {
+	Name    string   `yaml:"name"`
+	Members []string `yaml:"members"`
+	Groups  []string `yaml:"groups"`
+}
+
+type TeamFile struct {
+	Teams []Team `yaml:"teams"`
+}
+
+type GroupMemberResult struct {
+	StartAt    int    `json:"startAt"`
+	MaxResults int    `json:"maxResults"`
+	Total      int    `json:"total"`
+	IsLast     bool   `json:"isLast"`
+	Values     []User `json:"values"`
+}
+
+type TeamTotal struct {
+	Team      string
+	Author    User
+	Timespent TimeTotal
+}
+
+func LoadTeams(r io.Reader) ([]Team, error) {
+
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ioutil.ReadAll error: %v", err)
+	}
+
+	var teamFile TeamFile
+	if err := yaml.Unmarshal(body, &teamFile); err != nil {
+		return nil, fmt.Errorf("yaml.Unmarshal error: %v\nbody=[%v]", err, string(body))
+	}
+
+	for _, team := range teamFile.Teams {
+		if len(team.Name) == 0 {
+			return nil, fmt.Errorf("empty team name\nteam=[%v]", team)
+		}
+	}
+
+	return teamFile.Teams, nil
+}
+
+func (c *Config) teams() ([]Team, error) {
+
+	teams := make([]Team, 0, 10)
+
+	if len(c.Teams) > 0 {
+		f, err := os.Open(c.Teams)
+		if err != nil {
+			return nil, fmt.Errorf("os.Open error: %v\nTeams=[%v]", err, c.Teams)
+		}
+		defer f.Close()
+
+		fileTeams, err := LoadTeams(f)
+		if err != nil {
+			return nil, fmt.Errorf("LoadTeams error: %v\nTeams=[%v]", err, c.Teams)
+		}
+		teams = append(teams, fileTeams...)
+	}
+
+	for _, group := range strings.Split(c.TeamGroups, ",") {
+		group = strings.TrimSpace(group)
+		if len(group) > 0 {
+			teams = append(teams, Team{Name: group, Groups: []string{group}})
+		}
+	}
+
+	return teams, nil
+}
+
+func (c *Config) validateAllocation() error {
+
+	switch c.TeamAllocation {
+	case allocationFirst, allocationSplit, allocationAll:
+		return nil
+	}
+
+	return fmt.Errorf("unknown team allocation: %v", c.TeamAllocation)
+}
+
+func getGroupMemberResult(site Site, groupname string, startAt int) (*GroupMemberResult, error) {
+
+	groupMemberURL, err := site.GroupMemberURL(groupname, startAt, config.MaxResult)
+	if err != nil {
+		return nil, fmt.Errorf("site.GroupMemberURL error: %v\ngroupname=[%v]", err, groupname)
+	}
+
+	req, err := http.NewRequest("GET", groupMemberURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequest error: %v\ngroupMemberURL=[%v]", err, groupMemberURL)
+	}
+
+	req.Header.Set("Authorization", site.basicAuthorization())
+	req.Header.Set("Accept", "application/json")
+
+	client := httpClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("client.Do error: %v\nreq=[%v]", err, req)
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ioutil.ReadAll error: %v\nresp.Body=[%v]", err, resp.Body)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %v\nresponseBody=[%v]", resp.Status, string(responseBody))
+	}
+
+	var result GroupMemberResult
+	if err := json.Unmarshal(responseBody, &result); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error: %v\nresponseBody=[%v]", err, responseBody)
+	}
+
+	return &result, nil
+}
+
+func getGroupMembers(site Site, groupname string) ([]User, error) {
+
+	cacheKey := site.cacheKey(fmt.Sprintf("getGroupMembers_%s", groupname))
+	if v, ok := cache.get(cacheKey); ok {
+		return v.([]User), nil
+	}
+
+	members := make([]User, 0, 10)
+	for startAt := 0; ; {
+		result, err := getGroupMemberResult(site, groupname, startAt)
+		if err != nil {
+			return nil, fmt.Errorf("getGroupMemberResult error: %v\ngroupname=[%v],startAt=[%v]", err, groupname, startAt)
+		}
+
+		members = append(members, result.Values...)
+		if result.IsLast || len(result.Values) == 0 {
+			break
+		}
+		startAt += len(result.Values)
+	}
+
+	cache.put(cacheKey, members)
+	return members, nil
+}
+
+func resolveTeamMembers(teams []Team) ([]Team, []error) {
+
+	resolveErrors := make([]error, 0, 10)
+
+	sites, err := config.sites()
+	if err != nil {
+		return nil, append(resolveErrors, fmt.Errorf("config.sites error: %v", err))
+	}
+
+	resolved := make([]Team, 0, len(teams))
+	for _, team := range teams {
+		members := make([]string, 0, len(team.Members))
+		members = append(members, team.Members...)
+
+		for _, group := range team.Groups {
+			groupErrors := make([]error, 0, len(sites))
+			for _, site := range sites {
+				users, err := getGroupMembers(site, group)
+				if err != nil {
+					groupErrors = append(groupErrors, fmt.Errorf("getGroupMembers error: %v\nteam=[%v],site=[%v]", err, team.Name, site.Name))
+					continue
+				}
+				for _, user := range users {
+					members = append(members, user.AccountId)
+					if len(user.Emailaddress) > 0 {
+						members = append(members, user.Emailaddress)
+					}
+				}
+			}
+			if len(groupErrors) == len(sites) {
+				resolveErrors = append(resolveErrors, groupErrors...)
+			}
+		}
+
+		team.Members = members
+		resolved = append(resolved, team)
+	}
+
+	return resolved, resolveErrors
+}
+
+func teamsOf(teams []Team, author User) []string {
+
+	names := make([]string, 0, 2)
+	for _, team := range teams {
+		for _, member := range team.Members {
+			if author.matches(member) {
+				names = append(names, team.Name)
+				break
+			}
+		}
+	}
+
+	return names
+}
+
+func allocate(second int, teams []string, rule string) map[string]int {
+
+	if len(teams) == 0 {
+		return map[string]int{unassignedTeam: second}
+	}
+
+	allocation := map[string]int{}
+	switch rule {
+	case allocationAll:
+		for _, team := range teams {
+			allocation[team] = second
+		}
+	case allocationSplit:
+		portion := second / len(teams)
+		for _, team := range teams {
+			allocation[team] = portion
+		}
+		allocation[teams[0]] += second - portion*len(teams)
+	default:
+		allocation[teams[0]] = second
+	}
+
+	return allocation
+}
+
+func (worklogs Worklogs) TeamTotals(teams []Team, rule string) []TeamTotal {
+
+	totals := map[string]*TeamTotal{}
+	for _, worklog := range worklogs {
+		for team, second := range allocate(worklog.Timespentseconds, teamsOf(teams, worklog.Author), rule) {
+			key := team + "\t" + worklog.authorKey()
+			total, ok := totals[key]
+			if !ok {
+				total = &TeamTotal{Team: team, Author: worklog.Author}
+				totals[key] = total
+			}
+			total.Timespent.Add(second)
+		}
+	}
+
+	order := map[string]int{unassignedTeam: len(teams)}
+	for i := len(teams) - 1; i >= 0; i-- {
+		order[teams[i].Name] = i
+	}
+
+	result := make([]TeamTotal, 0, len(totals))
+	for _, total := range totals {
+		result = append(result, *total)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Team != result[j].Team {
+			return order[result[i].Team] < order[result[j].Team]
+		}
+		if result[i].Author.Emailaddress == result[j].Author.Emailaddress {
+			return result[i].Author.Displayname < result[j].Author.Displayname
+		}
+		return result[i].Author.Emailaddress < result[j].Author.Emailaddress
+	})
+
+	return result
+}
+
+func (results WorklogResults) TeamTables() (Tables, error) {
+
+	if err := config.validateAllocation(); err != nil {
+		return nil, err
+	}
+
+	teams, err := config.teams()
+	if err != nil {
+		return nil, fmt.Errorf("config.teams error: %v", err)
+	}
+
+	teams, resolveErrors := resolveTeamMembers(teams)
+	if len(resolveErrors) > 0 {
+		return nil, fmt.Errorf("resolveTeamMembers error: %v", resolveErrors)
+	}
+
+	table := newTable("チーム", "表示名", "メールアドレス", "アカウントID", "消費時間")
+
+	worklogs := results.inTargetMonth()
+	totals := worklogs.TeamTotals(teams, config.TeamAllocation)
+	var subtotal, grandTotal TimeTotal
+	for i, total := range totals {
+		table.append([]string{
+			total.Team,
+			total.Author.Displayname,
+			total.Author.Emailaddress,
+			total.Author.AccountId,
+			total.Timespent.String(),
+		})
+		subtotal = subtotal.Plus(total.Timespent)
+		grandTotal = grandTotal.Plus(total.Timespent)
+
+		if i == len(totals)-1 || totals[i+1].Team != total.Team {
+			table.append([]string{total.Team, "小計", "", "", subtotal.String()})
+			subtotal = TimeTotal{}
+		}
+	}
+
+	if config.TeamAllocation == allocationAll {
+		grandTotal = TimeTotal{}
+		for _, worklog := range worklogs {
+			grandTotal.Add(worklog.Timespentseconds)
+		}
+	}
+	table.append([]string{"合計", "", "", "", grandTotal.String()})
+
+	return Tables{table}, nil
+}
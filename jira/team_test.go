@@ -0,0 +1,50 @@
+package jira
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWorklogs_TeamTotals(t *testing.T) {
+	teams, err := LoadTeams(strings.NewReader(`
+teams:
+  - name: Platform
+    members:
+      - alice@example.com
+      - 5b10a2844c20165700ede21g
+  - name: Web
+    members:
+      - 5b10a2844c20165700ede21g
+`))
+	if err != nil {
+		t.Fatalf("LoadTeams() error = %v", err)
+	}
+
+	worklogs := Worklogs{
+		{Key: "TEST-1", Author: User{Emailaddress: "alice@example.com"}, Timespentseconds: 60 * 60},
+		{Key: "TEST-1", Author: User{AccountId: "5b10a2844c20165700ede21g"}, Timespentseconds: 60*60 + 1},
+		{Key: "TEST-2", Author: User{Emailaddress: "carol@example.com"}, Timespentseconds: 30 * 60},
+	}
+
+	tests := []struct {
+		rule string
+		want map[string]int
+	}{
+		{rule: allocationFirst, want: map[string]int{"Platform": 2*60*60 + 1, "Web": 0, unassignedTeam: 30 * 60}},
+		{rule: allocationSplit, want: map[string]int{"Platform": 60*60 + 30*60 + 1, "Web": 30 * 60, unassignedTeam: 30 * 60}},
+		{rule: allocationAll, want: map[string]int{"Platform": 2*60*60 + 1, "Web": 60*60 + 1, unassignedTeam: 30 * 60}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got := map[string]int{}
+			for _, total := range worklogs.TeamTotals(teams, tt.rule) {
+				got[total.Team] += total.Timespent.Seconds()
+			}
+			for team, want := range tt.want {
+				if got[team] != want {
+					t.Errorf("TeamTotals() team=[%v] got = %v, want %v", team, got[team], want)
+				}
+			}
+		})
+	}
+}
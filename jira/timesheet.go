@@ -0,0 +1,112 @@
+package jira
+
+import (
+	"sort"
+)
+
+type AuthorTotal struct {
+	Query     string
+	Author    User
+	Timespent TimeTotal
+}
+
+type AuthorTotals []AuthorTotal
+
+func (worklogs Worklogs) AuthorTotals(byQuery bool) AuthorTotals {
+
+	totals := map[string]*AuthorTotal{}
+	for _, worklog := range worklogs {
+		query := ""
+		if byQuery && len(worklog.QueryNames) > 0 {
+			query = worklog.QueryNames[0]
+		}
+
+		key := query + "\t" + worklog.authorKey()
+		total, ok := totals[key]
+		if !ok {
+			total = &AuthorTotal{Query: query, Author: worklog.Author}
+			totals[key] = total
+		}
+		total.Timespent.Add(worklog.Timespentseconds)
+	}
+
+	order := map[string]int{}
+	for i, q := range config.namedQueries() {
+		if _, ok := order[q.Name]; !ok {
+			order[q.Name] = i
+		}
+	}
+
+	result := make(AuthorTotals, 0, len(totals))
+	for _, total := range totals {
+		result = append(result, *total)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Query != result[j].Query {
+			return order[result[i].Query] < order[result[j].Query]
+		}
+		if result[i].Author.Emailaddress == result[j].Author.Emailaddress {
+			return result[i].Author.Displayname < result[j].Author.Displayname
+		}
+		return result[i].Author.Emailaddress < result[j].Author.Emailaddress
+	})
+
+	return result
+}
+
+func (results WorklogResults) TimesheetTables() (Tables, error) {
+
+	if _, err := config.userDirectory(); err != nil {
+		return nil, err
+	}
+	expectedSeconds, err := config.ExpectedSeconds()
+	if err != nil {
+		return nil, err
+	}
+	expected := NewTimeTotal(expectedSeconds)
+
+	authorFields := append([]string{"author.displayname", "author.emailaddress", "author.accountid"}, config.userFields()...)
+
+	byQuery := config.hasNamedQueries()
+
+	fieldLabels := make([]string, 0, len(authorFields)+4)
+	if byQuery {
+		fieldLabels = append(fieldLabels, defaultFieldText["query"])
+	}
+	for _, field := range authorFields {
+		fieldLabels = append(fieldLabels, defaultFieldText[field])
+	}
+	fieldLabels = append(fieldLabels, "消費時間", "所定時間", "差分")
+	table := newTable(fieldLabels...)
+
+	totals := results.inTargetMonth().AuthorTotals(byQuery)
+	var subtotal TimeTotal
+	for i, total := range totals {
+		difference := total.Timespent.Minus(expected)
+		record := make([]string, 0, len(fieldLabels))
+		if byQuery {
+			record = append(record, total.Query)
+		}
+		for _, field := range authorFields {
+			v, _ := total.Author.field(field)
+			record = append(record, v)
+		}
+		record = append(record, total.Timespent.String(), expected.String(), difference.String())
+		table.append(record)
+
+		if !byQuery {
+			continue
+		}
+		subtotal = subtotal.Plus(total.Timespent)
+		if i == len(totals)-1 || totals[i+1].Query != total.Query {
+			record := make([]string, len(fieldLabels))
+			record[0] = total.Query
+			record[1] = "小計"
+			record[len(record)-3] = subtotal.String()
+			table.append(record)
+			subtotal = TimeTotal{}
+		}
+	}
+
+	return Tables{table}, nil
+}
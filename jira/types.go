@@ -5,40 +5,96 @@ type Status struct {
 	Description string `json:"description,omitempty"`
 }
 
+type IssueType struct {
+	Name           string `json:"name,omitempty"`
+	HierarchyLevel int    `json:"hierarchyLevel,omitempty"`
+}
+
+type Project struct {
+	Key  string `json:"key,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type ParentIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary   string    `json:"summary"`
+		Issuetype IssueType `json:"issuetype,omitempty"`
+	} `json:"fields"`
+}
+
 type IssueField struct {
-	Summary                       string `json:"summary"`
-	Timespent                     int    `json:"timespent"`
-	Timeoriginalestimate          int    `json:"timeoriginalestimate"`
-	Aggregatetimespent            int    `json:"aggregatetimespent"`
-	Aggregatetimeoriginalestimate int    `json:"aggregatetimeoriginalestimate"`
-	Status                        Status `json:"status,omitempty"`
+	Summary                       string       `json:"summary"`
+	Timespent                     int          `json:"timespent"`
+	Timeoriginalestimate          int          `json:"timeoriginalestimate"`
+	Aggregatetimespent            int          `json:"aggregatetimespent"`
+	Aggregatetimeoriginalestimate int          `json:"aggregatetimeoriginalestimate"`
+	Status                        Status       `json:"status,omitempty"`
+	Issuetype                     IssueType    `json:"issuetype,omitempty"`
+	Created                       string       `json:"created,omitempty"`
+	Resolutiondate                string       `json:"resolutiondate,omitempty"`
+	Project                       Project      `json:"project,omitempty"`
+	Parent                        *ParentIssue `json:"parent,omitempty"`
+}
+
+type ChangeItem struct {
+	Field      string `json:"field"`
+	FromString string `json:"fromString"`
+	ToString   string `json:"toString"`
+}
+
+type History struct {
+	Id      string       `json:"id"`
+	Created string       `json:"created"`
+	Items   []ChangeItem `json:"items"`
+}
+
+type Changelog struct {
+	StartAt    int       `json:"startAt"`
+	MaxResults int       `json:"maxResults"`
+	Total      int       `json:"total"`
+	Histories  []History `json:"histories"`
 }
 
 type Issue struct {
-	Id     string     `json:"id"`
-	Key    string     `json:"key"`
-	Fields IssueField `json:"fields"`
+	Id         string     `json:"id"`
+	Key        string     `json:"key"`
+	Fields     IssueField `json:"fields"`
+	Changelog  Changelog  `json:"changelog,omitempty"`
+	QueryNames []string   `json:"queryNames,omitempty"`
+	Site       string     `json:"site,omitempty"`
 }
 
 type Issues []Issue
 
 type IssueSearchResult struct {
-	StartAt    int    `json:"startAt"`
-	Total      int    `json:"total"`
-	MaxResults int    `json:"maxResults"`
-	Issues     Issues `json:"issues"`
+	StartAt       int    `json:"startAt"`
+	Total         int    `json:"total"`
+	MaxResults    int    `json:"maxResults"`
+	Issues        Issues `json:"issues"`
+	NextPageToken string `json:"nextPageToken,omitempty"`
+	IsLast        bool   `json:"isLast"`
 }
 
 type IssueSearchResults []IssueSearchResult
 
+type User struct {
+	AccountId    string `json:"accountId"`
+	Displayname  string `json:"displayName"`
+	Emailaddress string `json:"emailAddress"`
+}
+
 type WorklogField struct {
-	Key    string
-	Author struct {
-		Displayname  string `json:"displayName"`
-		Emailaddress string `json:"emailAddress"`
-	} `json:"author"`
-	Started          string `json:"started"`
-	Timespentseconds int    `json:"timespentSeconds"`
+	Key              string
+	Id               string   `json:"id"`
+	Author           User     `json:"author"`
+	Started          string   `json:"started"`
+	Created          string   `json:"created,omitempty"`
+	Updated          string   `json:"updated,omitempty"`
+	UpdateAuthor     *User    `json:"updateAuthor,omitempty"`
+	Timespentseconds int      `json:"timespentSeconds"`
+	QueryNames       []string `json:"queryNames,omitempty"`
+	Site             string   `json:"site,omitempty"`
 }
 
 type Worklogs []WorklogField
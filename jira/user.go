@@ -0,0 +1,236 @@
+package jira
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+type UserEntry struct {
+	AccountId  string
+	EmployeeId string
+	Team       string
+	CostCentre string
+}
+
+type UserDirectory map[string]UserEntry
+
+func LoadUserDirectory(r io.Reader) (UserDirectory, error) {
+
+	directory := UserDirectory{}
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		values := strings.Split(line, ",")
+		for len(values) < 4 {
+			values = append(values, "")
+		}
+		entry := UserEntry{
+			AccountId:  strings.TrimSpace(values[0]),
+			EmployeeId: strings.TrimSpace(values[1]),
+			Team:       strings.TrimSpace(values[2]),
+			CostCentre: strings.TrimSpace(values[3]),
+		}
+		if len(entry.AccountId) == 0 {
+			return nil, fmt.Errorf("empty accountId\nlineNumber=[%v],line=[%v]", lineNumber, line)
+		}
+		directory[entry.AccountId] = entry
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanner.Err error: %v", err)
+	}
+
+	return directory, nil
+}
+
+func (c *Config) userDirectory() (UserDirectory, error) {
+
+	cacheKey := fmt.Sprintf("userDirectory_%s", c.Users)
+	if v, ok := cache.get(cacheKey); ok {
+		return v.(UserDirectory), nil
+	}
+
+	directory := UserDirectory{}
+	if len(c.Users) > 0 {
+		f, err := os.Open(c.Users)
+		if err != nil {
+			return nil, fmt.Errorf("os.Open error: %v\nUsers=[%v]", err, c.Users)
+		}
+		defer f.Close()
+
+		directory, err = LoadUserDirectory(f)
+		if err != nil {
+			return nil, fmt.Errorf("LoadUserDirectory error: %v\nUsers=[%v]", err, c.Users)
+		}
+	}
+
+	cache.put(cacheKey, directory)
+	return directory, nil
+}
+
+func (c *Config) userFields() []string {
+
+	if len(c.Users) == 0 {
+		return []string{}
+	}
+
+	return []string{
+		"author.employeeid",
+		"author.team",
+		"author.costcentre",
+	}
+}
+
+func (u *User) field(fieldName string) (string, bool) {
+
+	switch fieldName {
+	case "author.accountid":
+		return u.AccountId, true
+	case "author.displayname":
+		return u.Displayname, true
+	case "author.emailaddress":
+		return u.Emailaddress, true
+	}
+
+	directory, err := config.userDirectory()
+	if err != nil {
+		return "", false
+	}
+
+	entry := directory[u.AccountId]
+	switch fieldName {
+	case "author.employeeid":
+		return entry.EmployeeId, true
+	case "author.team":
+		return entry.Team, true
+	case "author.costcentre":
+		return entry.CostCentre, true
+	}
+
+	return "", false
+}
+
+func (u *User) key() string {
+
+	if config.multiSite() && len(u.Emailaddress) > 0 {
+		return u.Emailaddress
+	}
+
+	if len(u.AccountId) > 0 {
+		return u.AccountId
+	}
+
+	if len(u.Emailaddress) > 0 {
+		return u.Emailaddress
+	}
+
+	return u.Displayname
+}
+
+func (u *User) matches(value string) bool {
+
+	if len(value) == 0 {
+		return false
+	}
+
+	return value == u.AccountId || value == u.Emailaddress || value == u.Displayname
+}
+
+func getUser(site Site, accountId string) (*User, error) {
+
+	cacheKey := site.cacheKey(fmt.Sprintf("getUser_%s", accountId))
+	if v, ok := cache.get(cacheKey); ok {
+		user := v.(User)
+		return &user, nil
+	}
+
+	userURL, err := site.UserURL(accountId)
+	if err != nil {
+		return nil, fmt.Errorf("site.UserURL error: %v\naccountId=[%v]", err, accountId)
+	}
+
+	req, err := http.NewRequest("GET", userURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequest error: %v\nuserURL=[%v]", err, userURL)
+	}
+
+	req.Header.Set("Authorization", site.basicAuthorization())
+	req.Header.Set("Accept", "application/json")
+
+	client := httpClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("client.Do error: %v\nreq=[%v]", err, req)
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ioutil.ReadAll error: %v\nresp.Body=[%v]", err, resp.Body)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %v\nresponseBody=[%v]", resp.Status, string(responseBody))
+	}
+
+	var user User
+	if err := json.Unmarshal(responseBody, &user); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error: %v\nresponseBody=[%v]", err, responseBody)
+	}
+
+	cache.put(cacheKey, user)
+	return &user, nil
+}
+
+func ResolveAuthors(results WorklogResults) []error {
+
+	resolveErrors := make([]error, 0, 10)
+	failed := map[string]bool{}
+
+	for i := range results {
+		for j := range results[i].Worklogs {
+			worklog := &results[i].Worklogs[j]
+			author := &worklog.Author
+			failedKey := worklog.Site + "\t" + author.AccountId
+			if len(author.AccountId) == 0 || failed[failedKey] {
+				continue
+			}
+			if len(author.Displayname) > 0 && len(author.Emailaddress) > 0 {
+				continue
+			}
+
+			site, err := config.site(worklog.Site)
+			if err != nil {
+				failed[failedKey] = true
+				resolveErrors = append(resolveErrors, fmt.Errorf("config.site error: %v\naccountId=[%v]", err, author.AccountId))
+				continue
+			}
+
+			user, err := getUser(site, author.AccountId)
+			if err != nil {
+				failed[failedKey] = true
+				resolveErrors = append(resolveErrors, fmt.Errorf("getUser error: %v\nsite=[%v],accountId=[%v]", err, worklog.Site, author.AccountId))
+				continue
+			}
+
+			if len(author.Displayname) == 0 {
+				author.Displayname = user.Displayname
+			}
+			if len(author.Emailaddress) == 0 {
+				author.Emailaddress = user.Emailaddress
+			}
+		}
+	}
+
+	return resolveErrors
+}
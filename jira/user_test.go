@@ -0,0 +1,60 @@
+package jira
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWorklogField_ToRecord(t *testing.T) {
+	directory, err := LoadUserDirectory(strings.NewReader("# accountId,employeeId,team,costCentre\n5b10a2844c20165700ede21g,E0001,Platform,CC100\n"))
+	if err != nil {
+		t.Fatalf("LoadUserDirectory() error = %v", err)
+	}
+	config.Users = "users.csv"
+	cache.put("userDirectory_users.csv", directory)
+	defer func() {
+		config.Users = ""
+	}()
+
+	worklog := WorklogField{
+		Key: "TEST-1",
+		Author: User{
+			AccountId:   "5b10a2844c20165700ede21g",
+			Displayname: "Alice",
+		},
+		Started: "2020-08-03T09:00:00.000+0900",
+	}
+
+	expected := []string{"TEST-1", "Alice", "", "5b10a2844c20165700ede21g", "E0001", "Platform", "CC100"}
+	actual := worklog.ToRecord([]string{"author.displayname", "author.emailaddress", "author.accountid", "author.employeeid", "author.team", "author.costcentre"})
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected=[%v] <> actual[%v]\n", expected, actual)
+	}
+
+	if _, err := LoadUserDirectory(strings.NewReader(",E0002,Platform,CC100\n")); err == nil {
+		t.Errorf("LoadUserDirectory() error = nil, want error")
+	}
+}
+
+func TestConfig_userDirectory(t *testing.T) {
+	saved := config.Users
+	defer func() {
+		config.Users = saved
+	}()
+	config.Users = "testdata/no-such-users.csv"
+
+	if _, err := config.userDirectory(); err == nil {
+		t.Errorf("userDirectory() error = nil, want error")
+	}
+
+	var buf strings.Builder
+	results := WorklogResults{{Worklogs: Worklogs{{Key: "TEST-1", Author: User{AccountId: "5b10a2844c20165700ede21g"}}}}}
+	if err := results.RenderCsv(&buf, []string{"author.accountid", "author.employeeid"}); err == nil {
+		t.Errorf("RenderCsv() error = nil, want error")
+	}
+	if _, err := results.TimesheetTables(); err == nil {
+		t.Errorf("TimesheetTables() error = nil, want error")
+	}
+}
@@ -1,19 +1,23 @@
 package jira
 
 import (
-	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"net/url"
 	"reflect"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
+var errEmptyWorklog = errors.New("empty result")
+
 func (a Worklogs) Len() int {
 
 	return len(a)
@@ -27,6 +31,9 @@ func (a Worklogs) Swap(i, j int) {
 func (a Worklogs) Less(i, j int) bool {
 
 	if a[i].Key == a[j].Key {
+		if a[i].Site != a[j].Site {
+			return a[i].Site < a[j].Site
+		}
 		return a[i].Started < a[j].Started
 	}
 
@@ -46,7 +53,7 @@ func (w *WorklogField) ToRecord(fields []string) []string {
 			switch fieldName {
 			case "timespentseconds":
 				second := int(field.Int())
-				v = fmt.Sprintf("%.2f", config.WithTimeUnit(second))
+				v = config.FormatTime(second)
 			default:
 				switch field.Kind() {
 				case reflect.String:
@@ -59,13 +66,13 @@ func (w *WorklogField) ToRecord(fields []string) []string {
 			}
 		}
 
-		if strings.HasPrefix(fieldName, "author.") {
-			if "author.displayname" == fieldName {
-				v = w.Author.Displayname
-			}
+		if fieldName == "query" {
+			v = strings.Join(w.QueryNames, ",")
+		}
 
-			if "author.emailaddress" == fieldName {
-				v = w.Author.Emailaddress
+		if strings.HasPrefix(fieldName, "author.") {
+			if value, ok := w.Author.field(fieldName); ok {
+				v = value
 			}
 		}
 
@@ -75,26 +82,58 @@ func (w *WorklogField) ToRecord(fields []string) []string {
 	return result
 }
 
-func (w *WorklogResult) IsNotEmpty() bool {
+func (w *WorklogField) StartedTime() (time.Time, error) {
 
-	return w.Total > 0 && len(w.Worklogs) > 0
+	return parseJiraTime(w.Started)
 }
 
-func (results WorklogResults) RenderCsv(w io.Writer, fields []string) error {
+func (w *WorklogField) siteKey() string {
+
+	if len(w.Site) == 0 {
+		return w.Key
+	}
+
+	return fmt.Sprintf("%s/%s", w.Site, w.Key)
+}
+
+func (w *WorklogField) authorKey() string {
+
+	return w.Author.key()
+}
+
+func (results WorklogResults) inTargetMonth() Worklogs {
 
-	fieldLabels := []string{"キー"}
-	for _, field := range fields {
-		label := field
-		if text, ok := defaultFieldText[label]; ok {
-			label = text
+	worklogs := make(Worklogs, 0, 10)
+	for _, result := range results {
+		for _, worklog := range result.Worklogs {
+			started, err := worklog.StartedTime()
+			if err != nil {
+				log.Printf("worklog.StartedTime error: %v\nkey=[%v],started=[%v]\n", err, worklog.Key, worklog.Started)
+				continue
+			}
+			if config.inTargetMonth(started) {
+				worklogs = append(worklogs, worklog)
+			}
 		}
-		fieldLabels = append(fieldLabels, label)
 	}
-	writer := csv.NewWriter(w)
-	if err := writer.Write(fieldLabels); err != nil {
-		return fmt.Errorf("writer.Write error: %v\nfieldLabels=[%v]\n", err, fieldLabels)
+	sort.Sort(worklogs)
+
+	return worklogs
+}
+
+func (w *WorklogResult) IsNotEmpty() bool {
+
+	return w.Total > 0 && len(w.Worklogs) > 0
+}
+
+func (results WorklogResults) Table(fields []string) (*Table, error) {
+
+	if _, err := config.userDirectory(); err != nil {
+		return nil, err
 	}
 
+	table := newTable(fieldLabels(fields)...)
+
 	allWorklogs := make(Worklogs, 0, 10)
 	for _, result := range results {
 		allWorklogs = append(allWorklogs, result.Worklogs...)
@@ -102,25 +141,27 @@ func (results WorklogResults) RenderCsv(w io.Writer, fields []string) error {
 	sort.Sort(allWorklogs)
 
 	for _, worklog := range allWorklogs {
-		record := worklog.ToRecord(fields)
-		if err := writer.Write(record); err != nil {
-			return fmt.Errorf("writer.Write error: %v\nrecord=[%v]\n", err, record)
-		}
+		table.append(worklog.ToRecord(fields))
 	}
 
-	writer.Flush()
-	if err := writer.Error(); err != nil {
-		return fmt.Errorf("writer.Error error: %v\n", err)
+	return &table, nil
+}
+
+func (results WorklogResults) RenderCsv(w io.Writer, fields []string) error {
+
+	table, err := results.Table(fields)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return Tables{*table}.RenderCsv(w)
 }
 
-func getWorklogResult(key string, queryParams url.Values) (*WorklogResult, error) {
+func getWorklogResult(site Site, key string, queryParams url.Values) (*WorklogResult, error) {
 
-	worklogURL, err := config.WorklogURL(key, queryParams)
+	worklogURL, err := site.WorklogURL(key, queryParams)
 	if err != nil {
-		return nil, fmt.Errorf("config.WorklogURL error: %v\nkey=[%v], queryParams=[%v]", err, key, queryParams)
+		return nil, fmt.Errorf("site.WorklogURL error: %v\nkey=[%v], queryParams=[%v]", err, key, queryParams)
 	}
 
 	req, err := http.NewRequest("GET", worklogURL.String(), nil)
@@ -128,10 +169,10 @@ func getWorklogResult(key string, queryParams url.Values) (*WorklogResult, error
 		return nil, fmt.Errorf("http.NewRequest error: %v\nworklogURL=[%v]", err, worklogURL)
 	}
 
-	req.Header.Set("Authorization", config.basicAuthorization())
+	req.Header.Set("Authorization", site.basicAuthorization())
 	req.Header.Set("Accept", "application/json")
 
-	client := &http.Client{}
+	client := httpClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("client.Do error: %v\nreq=[%v]", err, req)
@@ -143,64 +184,162 @@ func getWorklogResult(key string, queryParams url.Values) (*WorklogResult, error
 	if err != nil {
 		return nil, fmt.Errorf("ioutil.ReadAll error: %v\nresp.Body=[%v]", err, resp.Body)
 	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %v\nresponseBody=[%v]", resp.Status, string(responseBody))
+	}
 	if err := json.Unmarshal(responseBody, &result); err != nil {
 		return nil, fmt.Errorf("json.Unmarshal error: %v\nresponseBody=[%v]", err, responseBody)
 	}
 
 	for i := range result.Worklogs {
 		result.Worklogs[i].Key = key
+		result.Worklogs[i].Site = site.Name
 	}
 
 	return &result, nil
 }
 
-func worklogCh(results IssueSearchResults) (<-chan *WorklogResult, <-chan error) {
+type worklogStream struct {
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	seen     map[string]bool
+	queue    Issues
+	closed   bool
+	resultCh chan *WorklogResult
+	errorCh  chan error
+	wg       sync.WaitGroup
+	done     chan struct{}
+	results  WorklogResults
+	errors   []error
+}
 
-	bufferSize := 10
-	if len(results) > 0 {
-		bufferSize = results[0].Total
+func newWorklogStream() *worklogStream {
+
+	s := &worklogStream{
+		seen:     map[string]bool{},
+		queue:    Issues{},
+		resultCh: make(chan *WorklogResult, maxWorkerSize),
+		errorCh:  make(chan error, maxWorkerSize),
+		done:     make(chan struct{}),
+		results:  WorklogResults{},
+		errors:   []error{},
+	}
+	s.cond = sync.NewCond(&s.mutex)
+
+	s.wg.Add(maxWorkerSize)
+	for n := 0; n < maxWorkerSize; n++ {
+		go worklogWorker(n, s.next, s.resultCh, s.errorCh, &s.wg)
 	}
+	go s.collect()
 
-	resultCh := make(chan *WorklogResult, bufferSize)
-	defer close(resultCh)
-	errorCh := make(chan error, bufferSize)
-	defer close(errorCh)
+	return s
+}
 
-	if len(results) == 0 {
-		return resultCh, errorCh
+func (s *worklogStream) add(issues Issues) {
+
+	if s == nil {
+		return
 	}
 
-	workerSize := len(results)
-	if workerSize > maxWorkerSize {
-		workerSize = maxWorkerSize
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, issue := range issues {
+		if s.seen[issue.siteKey()] {
+			continue
+		}
+		s.seen[issue.siteKey()] = true
+		s.queue = append(s.queue, issue)
 	}
+	s.cond.Broadcast()
+}
+
+func (s *worklogStream) next() (Issue, bool) {
 
-	var wg sync.WaitGroup
-	wg.Add(workerSize)
-	keyCh := make(chan string, bufferSize)
-	for n := 0; n < workerSize; n++ {
-		go worklogWorker(n, keyCh, resultCh, errorCh, &wg)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for len(s.queue) == 0 && !s.closed {
+		s.cond.Wait()
 	}
+	if len(s.queue) == 0 {
+		return Issue{}, false
+	}
+
+	issue := s.queue[0]
+	s.queue = s.queue[1:]
+	return issue, true
+}
+
+func (s *worklogStream) collect() {
 
-	for _, searchResult := range results {
-		for _, issue := range searchResult.Issues {
-			keyCh <- issue.Key
+	defer close(s.done)
+	resultCh, errorCh := s.resultCh, s.errorCh
+	for resultCh != nil || errorCh != nil {
+		select {
+		case result, ok := <-resultCh:
+			if !ok {
+				resultCh = nil
+				continue
+			}
+			s.results = append(s.results, *result)
+		case err, ok := <-errorCh:
+			if !ok {
+				errorCh = nil
+				continue
+			}
+			s.errors = append(s.errors, err)
+		}
+	}
+}
+
+func (s *worklogStream) wait(issues IssueSearchResults) (WorklogResults, []error) {
+
+	s.mutex.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+	close(s.resultCh)
+	close(s.errorCh)
+	<-s.done
+
+	queryNames := map[string][]string{}
+	for _, result := range issues {
+		for _, issue := range result.Issues {
+			queryNames[issue.siteKey()] = issue.QueryNames
+		}
+	}
+	for i := range s.results {
+		for j := range s.results[i].Worklogs {
+			worklog := &s.results[i].Worklogs[j]
+			worklog.QueryNames = queryNames[worklog.siteKey()]
 		}
 	}
-	close(keyCh)
-	wg.Wait()
 
-	return resultCh, errorCh
+	return s.results, s.errors
 }
 
-func worklogWorker(n int, keyCh <-chan string, resultCh chan<- *WorklogResult, errorCh chan<- error, wg *sync.WaitGroup) {
+func worklogWorker(n int, next func() (Issue, bool), resultCh chan<- *WorklogResult, errorCh chan<- error, wg *sync.WaitGroup) {
 
 	defer wg.Done()
-	for key := range keyCh {
-		result, err := worklog(key)
+	for issue, ok := next(); ok; issue, ok = next() {
+		site, err := config.site(issue.Site)
 		if err != nil {
-			errorCh <- fmt.Errorf("worklog error: %v\nn=[%v],key=[%v]", err, n, key)
+			errorCh <- fmt.Errorf("config.site error: %v\nn=[%v],key=[%v]", err, n, issue.Key)
+			config.progress.worklog(issue.Site, issue.Key, 0)
+			continue
+		}
+
+		result, err := worklog(site, issue.Key)
+		if err != nil && err != errEmptyWorklog {
+			errorCh <- fmt.Errorf("worklog error: %v\nn=[%v],site=[%v],key=[%v]", err, n, issue.Site, issue.Key)
+		}
+
+		worklogs := 0
+		if result != nil {
+			worklogs = len(result.Worklogs)
 		}
+		config.progress.worklog(issue.Site, issue.Key, worklogs)
 
 		if result != nil {
 			resultCh <- result
@@ -208,7 +347,7 @@ func worklogWorker(n int, keyCh <-chan string, resultCh chan<- *WorklogResult, e
 	}
 }
 
-func worklog(key string) (*WorklogResult, error) {
+func worklog(site Site, key string) (*WorklogResult, error) {
 
 	queryParams := url.Values{
 		"startAt":      []string{"0"},
@@ -216,7 +355,7 @@ func worklog(key string) (*WorklogResult, error) {
 		"startedAfter": []string{config.StartedAfter()},
 	}
 
-	result, err := getWorklogResult(key, queryParams)
+	result, err := getWorklogResult(site, key, queryParams)
 	if err != nil {
 		return nil, fmt.Errorf("getWorklogResult error: %v\nkey=[%v], queryParams=[%v]",
 			err, key, queryParams)
@@ -226,5 +365,5 @@ func worklog(key string) (*WorklogResult, error) {
 		return result, nil
 	}
 
-	return nil, fmt.Errorf("empty result")
+	return nil, errEmptyWorklog
 }